@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+// client calls the HTTP API of the service.
+type client struct {
+	baseURL string
+	token   string
+	actor   string
+	http    *http.Client
+}
+
+func newClient(cfg config) *client {
+	return &client{
+		baseURL: strings.TrimRight(cfg.Server, "/"),
+		token:   cfg.Token,
+		actor:   cfg.Actor,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// apiError is an error response of the service.
+type apiError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (c *client) get(path string, query url.Values, out interface{}) error {
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	return c.do(http.MethodGet, path, nil, out)
+}
+
+func (c *client) post(path string, body, out interface{}) error {
+	return c.do(http.MethodPost, path, body, out)
+}
+
+func (c *client) do(method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.actor != "" {
+		req.Header.Set("X-Actor", c.actor)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		var errResp entities.ErrorResponse
+		if err := json.Unmarshal(data, &errResp); err != nil || errResp.Error.Code == "" {
+			return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+		}
+		return &apiError{Status: resp.StatusCode, Code: errResp.Error.Code, Message: errResp.Error.Message}
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+type cli struct {
+	name   string
+	client *client
+	out    *printer
+	stderr io.Writer
+}
+
+func (c *cli) flags() *flag.FlagSet {
+	fs := flag.NewFlagSet("prctl "+c.name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	return fs
+}
+
+// parse parses command flags and checks that the required string flags are
+// set.
+func (c *cli) parse(fs *flag.FlagSet, args []string, required ...string) error {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return err
+		}
+		return &usageError{}
+	}
+	if fs.NArg() > 0 {
+		return &usageError{message: fmt.Sprintf("unexpected argument %q", fs.Arg(0))}
+	}
+	for _, name := range required {
+		if fs.Lookup(name).Value.String() == "" {
+			return &usageError{message: fmt.Sprintf("-%s is required", name)}
+		}
+	}
+	return nil
+}
+
+// teamFile is a team definition in YAML or JSON. A file holds either one
+// team or a list of them under teams. Members are active unless is_active
+// says otherwise.
+type teamFile struct {
+	TeamName string           `yaml:"team_name"`
+	Members  []teamFileMember `yaml:"members"`
+	Teams    []teamFile       `yaml:"teams"`
+}
+
+type teamFileMember struct {
+	UserID   string `yaml:"user_id"`
+	Username string `yaml:"username"`
+	IsActive *bool  `yaml:"is_active"`
+}
+
+// readFile reads path, or standard input when path is "-".
+func readFile(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// readTeams loads the teams of a team file. JSON is valid YAML, so both are
+// parsed the same way.
+func readTeams(path string) ([]entities.Team, error) {
+	data, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file teamFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	defs := file.Teams
+	if file.TeamName != "" {
+		defs = append([]teamFile{file}, defs...)
+	}
+	if len(defs) == 0 {
+		return nil, fmt.Errorf("%s: no teams defined", path)
+	}
+
+	teams := make([]entities.Team, len(defs))
+	for i, def := range defs {
+		if def.TeamName == "" {
+			return nil, fmt.Errorf("%s: team %d has no team_name", path, i+1)
+		}
+		teams[i] = entities.Team{TeamName: def.TeamName, Members: []entities.TeamMember{}}
+		for _, m := range def.Members {
+			teams[i].Members = append(teams[i].Members, entities.TeamMember{
+				UserID:   m.UserID,
+				Username: m.Username,
+				IsActive: m.IsActive == nil || *m.IsActive,
+			})
+		}
+	}
+	return teams, nil
+}
+
+func (c *cli) teamAdd(args []string) error {
+	fs := c.flags()
+	file := fs.String("f", "", "team file in YAML or JSON, - for standard input")
+	if err := c.parse(fs, args, "f"); err != nil {
+		return err
+	}
+
+	teams, err := readTeams(*file)
+	if err != nil {
+		return err
+	}
+
+	// Teams are created one by one; on failure the ones already created are
+	// still printed.
+	created := make([]entities.Team, 0, len(teams))
+	var addErr error
+	for _, team := range teams {
+		var resp struct {
+			Team entities.Team `json:"team"`
+		}
+		if err := c.client.post("/team/add", team, &resp); err != nil {
+			addErr = fmt.Errorf("team %s: %w", team.TeamName, err)
+			break
+		}
+		created = append(created, resp.Team)
+	}
+	if len(created) == 0 {
+		return addErr
+	}
+
+	var result interface{} = created
+	if len(teams) == 1 {
+		result = created[0]
+	}
+	if err := c.out.print(result, func(w io.Writer) {
+		writeTeams(w, created)
+	}); err != nil {
+		return err
+	}
+	return addErr
+}
+
+func (c *cli) teamGet(args []string) error {
+	fs := c.flags()
+	name := fs.String("team", "", "team name")
+	if err := c.parse(fs, args, "team"); err != nil {
+		return err
+	}
+
+	var team entities.Team
+	if err := c.client.get("/team/get", url.Values{"team_name": {*name}}, &team); err != nil {
+		return err
+	}
+	return c.out.print(team, func(w io.Writer) {
+		writeTeams(w, []entities.Team{team})
+	})
+}
+
+// teamSync sends a desired-state document as is; the server rejects unknown
+// fields, so typos are not silently ignored.
+func (c *cli) teamSync(args []string) error {
+	fs := c.flags()
+	file := fs.String("f", "", "desired state in YAML or JSON, - for standard input")
+	dryRun := fs.Bool("dry-run", false, "print the plan without applying it")
+	if err := c.parse(fs, args, "f"); err != nil {
+		return err
+	}
+
+	data, err := readFile(*file)
+	if err != nil {
+		return err
+	}
+	var state interface{}
+	if err := yaml.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("parse %s: %w", *file, err)
+	}
+
+	path := "/team/sync"
+	if *dryRun {
+		path += "?dry_run=true"
+	}
+	var result entities.OrgSyncResult
+	if err := c.client.post(path, state, &result); err != nil {
+		return err
+	}
+
+	return c.out.print(result, func(w io.Writer) {
+		row(w, "ACTION", "TEAM", "USER", "CHANGES")
+		for _, change := range result.Changes {
+			details := formatChanges(change.Changes)
+			if change.PullRequestID != "" {
+				details = "pull_request_id=" + change.PullRequestID
+			}
+			row(w, change.Action, change.TeamName, orDash(change.UserID), orDash(details))
+		}
+		summary := fmt.Sprintf("%d changes, %d users unchanged", len(result.Changes), result.Unchanged)
+		if result.DryRun {
+			summary += " (dry run, nothing applied)"
+		}
+		fmt.Fprintln(w, summary)
+		for _, skip := range result.TopUpSkipped {
+			fmt.Fprintf(w, "could not top up %s: %s\n", skip.PullRequestID, skip.Error)
+		}
+	})
+}
+
+func formatChanges(changes map[string]entities.FieldChange) string {
+	fields := make([]string, 0, len(changes))
+	for field := range changes {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		c := changes[field]
+		if c.From == "" {
+			parts[i] = fmt.Sprintf("%s=%s", field, c.To)
+		} else {
+			parts[i] = fmt.Sprintf("%s: %s->%s", field, c.From, c.To)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func writeTeams(w io.Writer, teams []entities.Team) {
+	row(w, "TEAM", "USER_ID", "USERNAME", "ACTIVE")
+	for _, team := range teams {
+		if len(team.Members) == 0 {
+			row(w, team.TeamName, "-", "-", "-")
+		}
+		for _, m := range team.Members {
+			row(w, team.TeamName, m.UserID, m.Username, yesNo(m.IsActive))
+		}
+	}
+}
+
+func (c *cli) usersSetActive(args []string) error {
+	fs := c.flags()
+	userID := fs.String("user", "", "user id")
+	active := fs.Bool("active", true, "whether the user takes reviews")
+	if err := c.parse(fs, args, "user"); err != nil {
+		return err
+	}
+
+	var resp struct {
+		User entities.User `json:"user"`
+	}
+	body := map[string]interface{}{"user_id": *userID, "is_active": *active}
+	if err := c.client.post("/users/setIsActive", body, &resp); err != nil {
+		return err
+	}
+	return c.out.print(resp.User, func(w io.Writer) {
+		row(w, "USER_ID", "USERNAME", "TEAM", "ACTIVE", "MAX_OPEN_REVIEWS")
+		row(w, resp.User.ID, resp.User.Username, resp.User.TeamName, yesNo(resp.User.IsActive), formatInt(resp.User.MaxOpenReviews))
+	})
+}
+
+func (c *cli) prCreate(args []string) error {
+	fs := c.flags()
+	id := fs.String("id", "", "pull request id")
+	name := fs.String("name", "", "pull request name")
+	author := fs.String("author", "", "author user id")
+	if err := c.parse(fs, args, "id", "name", "author"); err != nil {
+		return err
+	}
+
+	var resp struct {
+		PR entities.PullRequest `json:"pr"`
+	}
+	body := map[string]string{"pull_request_id": *id, "pull_request_name": *name, "author_id": *author}
+	if err := c.client.post("/pullRequest/create", body, &resp); err != nil {
+		return err
+	}
+	return c.printPR(resp.PR, "")
+}
+
+func (c *cli) prMerge(args []string) error {
+	fs := c.flags()
+	id := fs.String("id", "", "pull request id")
+	if err := c.parse(fs, args, "id"); err != nil {
+		return err
+	}
+
+	var resp struct {
+		PR entities.PullRequest `json:"pr"`
+	}
+	if err := c.client.post("/pullRequest/merge", map[string]string{"pull_request_id": *id}, &resp); err != nil {
+		return err
+	}
+	return c.printPR(resp.PR, "")
+}
+
+func (c *cli) prReassign(args []string) error {
+	fs := c.flags()
+	id := fs.String("id", "", "pull request id")
+	oldUserID := fs.String("old", "", "reviewer to replace")
+	reason := fs.String("reason", "", "reason recorded in the PR history (default manual)")
+	if err := c.parse(fs, args, "id", "old"); err != nil {
+		return err
+	}
+
+	var resp struct {
+		PR         entities.PullRequest `json:"pr"`
+		ReplacedBy string               `json:"replaced_by"`
+	}
+	body := map[string]string{"pull_request_id": *id, "old_user_id": *oldUserID, "reason": *reason}
+	if err := c.client.post("/pullRequest/reassign", body, &resp); err != nil {
+		return err
+	}
+	if c.out.format == outputJSON {
+		return c.out.print(resp, nil)
+	}
+	return c.printPR(resp.PR, resp.ReplacedBy)
+}
+
+func (c *cli) printPR(pr entities.PullRequest, replacedBy string) error {
+	return c.out.print(pr, func(w io.Writer) {
+		columns := []string{"ID", "NAME", "AUTHOR", "STATUS", "REVIEWERS", "CREATED", "MERGED"}
+		values := []string{pr.ID, pr.Name, pr.AuthorID, pr.Status, orDash(strings.Join(pr.AssignedReviewers, ",")),
+			formatTime(pr.CreatedAt), formatTime(pr.MergedAt)}
+		if replacedBy != "" {
+			columns = append(columns, "REPLACED_BY")
+			values = append(values, replacedBy)
+		}
+		row(w, columns...)
+		row(w, values...)
+	})
+}
+
+func (c *cli) reviews(args []string) error {
+	fs := c.flags()
+	userID := fs.String("user", "", "reviewer user id")
+	status := fs.String("status", "", "OPEN (default), MERGED, CLOSED or ALL")
+	if err := c.parse(fs, args, "user"); err != nil {
+		return err
+	}
+
+	query := url.Values{"user_id": {*userID}}
+	if *status != "" {
+		query.Set("status", *status)
+	}
+
+	result := struct {
+		UserID       string                      `json:"user_id"`
+		PullRequests []entities.PullRequestShort `json:"pull_requests"`
+	}{UserID: *userID, PullRequests: []entities.PullRequestShort{}}
+	for {
+		var page struct {
+			PullRequests []entities.PullRequestShort `json:"pull_requests"`
+			NextCursor   string                      `json:"next_cursor"`
+		}
+		if err := c.client.get("/users/getReview", query, &page); err != nil {
+			return err
+		}
+		result.PullRequests = append(result.PullRequests, page.PullRequests...)
+		if page.NextCursor == "" {
+			break
+		}
+		query.Set("cursor", page.NextCursor)
+	}
+
+	return c.out.print(result, func(w io.Writer) {
+		row(w, "PR_ID", "NAME", "AUTHOR", "STATUS", "ASSIGNED", "AGE", "VERDICT")
+		for _, pr := range result.PullRequests {
+			row(w, pr.ID, pr.Name, pr.AuthorID, pr.Status, formatTime(pr.AssignedAt),
+				formatAge(pr.AgeSeconds), orDash(pr.Verdict))
+		}
+	})
+}
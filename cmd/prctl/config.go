@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+const defaultServer = "http://localhost:8080"
+
+// config holds the connection settings. Flags override environment
+// variables, which override the config file.
+type config struct {
+	Server string `yaml:"server"`
+	Token  string `yaml:"token"`
+	Actor  string `yaml:"actor"`
+	Output string `yaml:"output"`
+}
+
+// defaultConfigPath is $XDG_CONFIG_HOME/prctl/config.yaml or its platform
+// equivalent.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "prctl", "config.yaml")
+}
+
+// loadConfig reads the config file, if there is one, and applies the
+// PRCTL_* environment variables on top. A missing file is only an error when
+// the path was given explicitly.
+func loadConfig(path string) (config, error) {
+	var cfg config
+
+	explicit := path != ""
+	if !explicit {
+		path = getEnv("PRCTL_CONFIG", defaultConfigPath())
+		explicit = os.Getenv("PRCTL_CONFIG") != ""
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			if err := yaml.Unmarshal(data, &cfg); err != nil {
+				return cfg, fmt.Errorf("parse %s: %w", path, err)
+			}
+		} else if explicit || !errors.Is(err, fs.ErrNotExist) {
+			return cfg, err
+		}
+	}
+
+	cfg.Server = getEnv("PRCTL_SERVER", cfg.Server)
+	cfg.Token = getEnv("PRCTL_TOKEN", cfg.Token)
+	cfg.Actor = getEnv("PRCTL_ACTOR", cfg.Actor)
+	cfg.Output = getEnv("PRCTL_OUTPUT", cfg.Output)
+
+	if cfg.Server == "" {
+		cfg.Server = defaultServer
+	}
+	if cfg.Actor == "" {
+		cfg.Actor = os.Getenv("USER")
+	}
+	if cfg.Output == "" {
+		cfg.Output = outputTable
+	}
+	return cfg, nil
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,175 @@
+// Command prctl is a command line client for the PR reviewer service.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// exitCodes maps service error codes to exit codes so that scripts can tell
+// failures apart without parsing the output. Other errors exit with 1 and
+// usage errors with 2.
+var exitCodes = map[string]int{
+	entities.ErrBadRequest:        3,
+	entities.ErrNotFound:          4,
+	entities.ErrTeamExists:        5,
+	entities.ErrPRExists:          6,
+	entities.ErrPRMerged:          7,
+	entities.ErrPRClosed:          8,
+	entities.ErrNotAssigned:       9,
+	entities.ErrNoCandidate:       10,
+	entities.ErrUserReferenced:    11,
+	"UNAUTHORIZED":                12,
+	entities.ErrUnavailable:       13,
+	entities.ErrDeliveryNotFailed: 14,
+}
+
+type command struct {
+	usage string
+	run   func(c *cli, args []string) error
+}
+
+var commands = map[string]command{
+	"team add":         {"-f FILE", (*cli).teamAdd},
+	"team get":         {"-team NAME", (*cli).teamGet},
+	"team sync":        {"-f FILE [-dry-run]", (*cli).teamSync},
+	"users set-active": {"-user ID [-active=false]", (*cli).usersSetActive},
+	"pr create":        {"-id ID -name NAME -author USER_ID", (*cli).prCreate},
+	"pr merge":         {"-id ID", (*cli).prMerge},
+	"pr reassign":      {"-id ID -old USER_ID [-reason REASON]", (*cli).prReassign},
+	"reviews":          {"-user ID [-status OPEN|MERGED|CLOSED|ALL]", (*cli).reviews},
+}
+
+// usageError is reported with exit code 2. An empty message means the flag
+// set has already printed the problem.
+type usageError struct {
+	message string
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("prctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("config", "", "config file (default $PRCTL_CONFIG or "+defaultConfigPath()+")")
+	server := fs.String("server", "", "server URL (default $PRCTL_SERVER or "+defaultServer+")")
+	token := fs.String("token", "", "bearer token (default $PRCTL_TOKEN)")
+	actor := fs.String("actor", "", "author of changes for the audit log (default $PRCTL_ACTOR or $USER)")
+	output := fs.String("o", "", "output format: table or json (default $PRCTL_OUTPUT or table)")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: prctl [flags] <command> [command flags]")
+		fmt.Fprintln(stderr, "\nCommands:")
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(stderr, "  %s %s\n", name, commands[name].usage)
+		}
+		fmt.Fprintln(stderr, "\nFlags:")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	name, cmd, rest, ok := findCommand(fs.Args())
+	if !ok {
+		fs.Usage()
+		return exitUsage
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "prctl: %v\n", err)
+		return exitError
+	}
+	if *server != "" {
+		cfg.Server = *server
+	}
+	if *token != "" {
+		cfg.Token = *token
+	}
+	if *actor != "" {
+		cfg.Actor = *actor
+	}
+	if *output != "" {
+		cfg.Output = *output
+	}
+	if cfg.Output != outputTable && cfg.Output != outputJSON {
+		fmt.Fprintf(stderr, "prctl: unknown output format %q\n", cfg.Output)
+		return exitUsage
+	}
+
+	c := &cli{
+		name:   name,
+		client: newClient(cfg),
+		out:    &printer{w: stdout, format: cfg.Output},
+		stderr: stderr,
+	}
+	return exitCode(cmd.run(c, rest), stderr)
+}
+
+// findCommand matches the longest command name at the start of args.
+func findCommand(args []string) (string, command, []string, bool) {
+	for n := 2; n >= 1; n-- {
+		if len(args) < n {
+			continue
+		}
+		name := strings.Join(args[:n], " ")
+		if cmd, ok := commands[name]; ok {
+			return name, cmd, args[n:], true
+		}
+	}
+	return "", command{}, nil, false
+}
+
+func exitCode(err error, stderr io.Writer) int {
+	if err == nil {
+		return exitOK
+	}
+	if err == flag.ErrHelp {
+		return exitOK
+	}
+
+	var usageErr *usageError
+	if errors.As(err, &usageErr) {
+		if usageErr.message != "" {
+			fmt.Fprintf(stderr, "prctl: %v\n", err)
+		}
+		return exitUsage
+	}
+
+	fmt.Fprintf(stderr, "prctl: %v\n", err)
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		if code, ok := exitCodes[apiErr.Code]; ok {
+			return code
+		}
+	}
+	return exitError
+}
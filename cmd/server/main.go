@@ -5,13 +5,20 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"syscall"
 	"time"
+	_ "time/tzdata"
 
+	"github.com/alexalexbor04/pull_request_service/internal/grpcapi"
 	"github.com/alexalexbor04/pull_request_service/internal/handler"
+	"github.com/alexalexbor04/pull_request_service/internal/mail"
 	"github.com/alexalexbor04/pull_request_service/internal/repos"
 	"github.com/alexalexbor04/pull_request_service/internal/service"
 
@@ -27,9 +34,28 @@ func main() {
 	dbPassword := getEnv("DB_PASSWORD", "password")
 	dbName := getEnv("DB_NAME", "postgres")
 	serverPort := getEnv("SERVER_PORT", "8080")
-
+	grpcPort := getEnv("GRPC_PORT", "9090")
+	availabilitySyncInterval := getEnv("AVAILABILITY_SYNC_INTERVAL", "")
+	antiAffinityWindow := getEnv("ANTI_AFFINITY_WINDOW", "0")
+	maxReviewers := getEnv("MAX_REVIEWERS_PER_PR", "2")
+	topUpInterval := getEnv("TOPUP_INTERVAL", "")
+	slaCheckInterval := getEnv("SLA_CHECK_INTERVAL", "5m")
+	staleCheckInterval := getEnv("STALE_CHECK_INTERVAL", "1h")
+	webhookDeliveryInterval := getEnv("WEBHOOK_DELIVERY_INTERVAL", "5s")
+	notificationInterval := getEnv("NOTIFICATION_INTERVAL", "10s")
+	eventStreamInterval := getEnv("EVENT_STREAM_INTERVAL", "1s")
+	githubWebhookSecret := getEnv("GITHUB_WEBHOOK_SECRET", "")
+	gitlabWebhookToken := getEnv("GITLAB_WEBHOOK_TOKEN", "")
+	webhookSecretKey := getEnv("WEBHOOK_SECRET_KEY", "")
+	smtpAddr := getEnv("SMTP_ADDR", "")
+	smtpFrom := getEnv("SMTP_FROM", "pr-service@localhost")
+	smtpUsername := getEnv("SMTP_USERNAME", "")
+	smtpPassword := getEnv("SMTP_PASSWORD", "")
+
+	// Timestamps are stored without a zone as UTC, so CURRENT_TIMESTAMP and
+	// localtimestamp must be UTC too whatever the server's default is.
 	connStr := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable timezone=UTC",
 		dbHost, dbPort, dbUser, dbPassword, dbName,
 	)
 
@@ -71,7 +97,51 @@ func main() {
 
 	repo := repos.New(db)
 	svc := service.New(repo)
+
+	window, err := strconv.Atoi(antiAffinityWindow)
+	if err != nil || window < 0 {
+		log.Fatalf("Invalid ANTI_AFFINITY_WINDOW: %q", antiAffinityWindow)
+	}
+	svc.SetAntiAffinityWindow(window)
+
+	maxRev, err := strconv.Atoi(maxReviewers)
+	if err != nil {
+		log.Fatalf("Invalid MAX_REVIEWERS_PER_PR: %q", maxReviewers)
+	}
+	if err := svc.SetMaxReviewers(maxRev); err != nil {
+		log.Fatalf("Invalid MAX_REVIEWERS_PER_PR: %v", err)
+	}
+
+	if webhookSecretKey != "" {
+		svc.SetWebhookSecretKey(webhookSecretKey)
+		sealed, err := svc.SealWebhookSecrets()
+		if err != nil {
+			log.Fatalf("Failed to encrypt webhook secrets: %v", err)
+		}
+		if sealed > 0 {
+			log.Printf("Encrypted %d webhook secrets", sealed)
+		}
+	}
+
+	if smtpAddr != "" {
+		svc.SetMailer(&mail.Mailer{Addr: smtpAddr, From: smtpFrom, Username: smtpUsername, Password: smtpPassword})
+		log.Printf("Email notifications enabled via %s", smtpAddr)
+	}
+
 	h := handler.New(svc)
+	h.SetGitHubWebhookSecret(githubWebhookSecret)
+	h.SetGitLabWebhookToken(gitlabWebhookToken)
+
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	startJob(jobsCtx, "AVAILABILITY_SYNC_INTERVAL", availabilitySyncInterval, svc.RunAvailabilitySync)
+	startJob(jobsCtx, "TOPUP_INTERVAL", topUpInterval, svc.RunTopUp)
+	startJob(jobsCtx, "SLA_CHECK_INTERVAL", slaCheckInterval, svc.RunSLACheck)
+	startJob(jobsCtx, "STALE_CHECK_INTERVAL", staleCheckInterval, svc.RunStaleCheck)
+	startJob(jobsCtx, "WEBHOOK_DELIVERY_INTERVAL", webhookDeliveryInterval, svc.RunWebhookDelivery)
+	startJob(jobsCtx, "NOTIFICATION_INTERVAL", notificationInterval, svc.RunNotifications)
+	startJob(jobsCtx, "EVENT_STREAM_INTERVAL", eventStreamInterval, svc.RunEventStream)
 
 	mux := http.NewServeMux()
 	h.SetupRoutes(mux)
@@ -91,14 +161,30 @@ func main() {
 		}
 	}()
 
+	grpcServer := grpcapi.NewServer(svc)
+	if grpcPort != "" {
+		lis, err := net.Listen("tcp", ":"+grpcPort)
+		if err != nil {
+			log.Fatalf("gRPC server failed to listen: %v", err)
+		}
+		go func() {
+			log.Printf("gRPC server is listening on port %s", grpcPort)
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Fatalf("gRPC server failed: %v", err)
+			}
+		}()
+	}
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("Shutting down server...")
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	grpcServer.GracefulStop()
 	if err := server.Shutdown(ctx); err != nil {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
@@ -107,19 +193,43 @@ func main() {
 }
 
 func applyMigrations(db *sql.DB) error {
-	migrationSQL, err := os.ReadFile("migrations/001_init.sql")
+	files, err := filepath.Glob("migrations/*.sql")
 	if err != nil {
-		return fmt.Errorf("failed to read migration file: %w", err)
+		return fmt.Errorf("failed to list migration files: %w", err)
 	}
+	sort.Strings(files)
 
-	_, err = db.Exec(string(migrationSQL))
-	if err != nil {
-		return fmt.Errorf("failed to execute migration: %w", err)
+	for _, file := range files {
+		migrationSQL, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read migration file %s: %w", file, err)
+		}
+
+		_, err = db.Exec(string(migrationSQL))
+		if err != nil {
+			return fmt.Errorf("failed to execute migration %s: %w", file, err)
+		}
 	}
 
 	return nil
 }
 
+// startJob runs a background job every interval given by the env variable;
+// an empty value leaves the job disabled.
+func startJob(ctx context.Context, name, value string, run func(context.Context, time.Duration)) {
+	if value == "" {
+		return
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		log.Fatalf("Invalid %s: %q", name, value)
+	}
+
+	go run(ctx, interval)
+	log.Printf("Background job %s runs every %s", name, interval)
+}
+
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
 	if value == "" {
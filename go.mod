@@ -1,5 +1,18 @@
 module github.com/alexalexbor04/pull_request_service
 
-go 1.22.2
+go 1.25.0
 
-require github.com/lib/pq v1.10.9
+require (
+	github.com/graph-gophers/graphql-go v1.9.0
+	github.com/lib/pq v1.10.9
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20260414002931-afd174a4e478
+	google.golang.org/grpc v1.82.1
+	google.golang.org/protobuf v1.36.11
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	golang.org/x/net v0.53.0 // indirect
+	golang.org/x/sys v0.43.0 // indirect
+	golang.org/x/text v0.36.0 // indirect
+)
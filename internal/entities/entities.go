@@ -7,6 +7,28 @@ type User struct {
 	Username string `json:"username" db:"username"`
 	TeamName string `json:"team_name" db:"team_name"`
 	IsActive bool `json:"is_active" db:"is_active"`
+	MaxOpenReviews *int `json:"max_open_reviews,omitempty" db:"max_open_reviews"`
+	ReviewWeight float64 `json:"review_weight" db:"review_weight"`
+	Email string `json:"email,omitempty" db:"email"`
+}
+
+// UserLogin links an account on a code hosting service to a user.
+type UserLogin struct {
+	Provider string `json:"provider"`
+	Login string `json:"login"`
+	UserID string `json:"user_id"`
+}
+
+type UserFilter struct {
+	TeamName string
+	IsActive *bool
+	AfterID string
+	Limit int
+}
+
+type UserUpdate struct {
+	Username *string `json:"username"`
+	Email *string `json:"email"`
 }
 
 type TeamMember struct {
@@ -20,6 +42,52 @@ type Team struct {
 	Members []TeamMember `json:"members"`
 }
 
+type MemberCapacity struct {
+	UserID string `json:"user_id"`
+	Username string `json:"username"`
+	IsActive bool `json:"is_active"`
+	OpenReviews int `json:"open_reviews"`
+	MaxOpenReviews *int `json:"max_open_reviews"`
+	Remaining *int `json:"remaining"`
+}
+
+type CandidateProbability struct {
+	UserID string `json:"user_id"`
+	Username string `json:"username"`
+	Weight float64 `json:"weight"`
+	Probability float64 `json:"probability"`
+}
+
+type TeamSLA struct {
+	TeamName string `json:"team_name"`
+	ReviewSLAHours *int `json:"review_sla_hours"`
+	ReassignAfterHours *int `json:"reassign_after_hours"`
+}
+
+// SLAAssignment is an open review assignment without a verdict together with
+// the SLA of the PR author's team. Now is the database clock.
+type SLAAssignment struct {
+	PullRequestID string
+	PullRequestName string
+	UserID string
+	TeamName string
+	AssignedAt time.Time
+	BreachedAt *time.Time
+	Now time.Time
+	SLA TeamSLA
+}
+
+type OverdueReview struct {
+	PullRequestID string `json:"pull_request_id"`
+	PullRequestName string `json:"pull_request_name"`
+	UserID string `json:"user_id"`
+	TeamName string `json:"team_name"`
+	AssignedAt time.Time `json:"assigned_at"`
+	ElapsedHours float64 `json:"elapsed_hours"`
+	SLAHours int `json:"sla_hours"`
+	BreachedAt *time.Time `json:"breached_at,omitempty"`
+}
+
 type PullRequest struct {
 	ID string `json:"pull_request_id" db:"id"`
 	Name string `json:"pull_request_name" db:"name"`
@@ -28,6 +96,52 @@ type PullRequest struct {
 	AssignedReviewers []string `json:"assigned_reviewers"`
 	CreatedAt *time.Time `json:"createdAt,omitempty" db:"created_at"`
 	MergedAt *time.Time `json:"mergedAt,omitempty" db:"merged_at"`
+	ClosedAt *time.Time `json:"closedAt,omitempty" db:"closed_at"`
+	Labels []string `json:"labels" db:"labels"`
+}
+
+// StalePolicy ages out idle OPEN pull requests of a team's authors: a warning
+// after WarnAfterDays and closing after CloseAfterDays without activity.
+// Pull requests labelled ExemptLabel are left alone.
+type StalePolicy struct {
+	TeamName string `json:"team_name"`
+	WarnAfterDays *int `json:"warn_after_days"`
+	CloseAfterDays *int `json:"close_after_days"`
+	ExemptLabel string `json:"exempt_label"`
+}
+
+type StalePR struct {
+	PullRequestID string `json:"pull_request_id"`
+	PullRequestName string `json:"pull_request_name"`
+	AuthorID string `json:"author_id"`
+	TeamName string `json:"team_name"`
+	LastActivityAt time.Time `json:"last_activity_at"`
+	IdleDays float64 `json:"idle_days"`
+	WarnedAt *time.Time `json:"warned_at,omitempty"`
+	Action string `json:"action"`
+	Now time.Time `json:"-"`
+	Policy StalePolicy `json:"-"`
+}
+
+const (
+	StaleActionWarn = "warn"
+	StaleActionClose = "close"
+)
+
+type PRFilter struct {
+	Status string
+	AuthorID string
+	ReviewerID string
+	TeamName string
+	NameContains string
+	CreatedFrom *time.Time
+	CreatedTo *time.Time
+	MergedFrom *time.Time
+	MergedTo *time.Time
+	Ascending bool
+	AfterCreatedAt *time.Time
+	AfterID string
+	Limit int
 }
 
 type PullRequestShort struct {
@@ -35,6 +149,304 @@ type PullRequestShort struct {
 	Name string `json:"pull_request_name"`
 	AuthorID string `json:"author_id"`
 	Status string `json:"status"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	AssignedAt *time.Time `json:"assigned_at,omitempty"`
+	AgeSeconds int64 `json:"age_seconds"`
+	Verdict string `json:"verdict,omitempty"`
+	HasVerdict bool `json:"has_verdict"`
+}
+
+type ReviewQueueFilter struct {
+	Status string
+	AfterAssignedAt *time.Time
+	AfterID string
+	Limit int
+}
+
+type Availability struct {
+	ID int64 `json:"id"`
+	UserID string `json:"user_id"`
+	StartDate string `json:"start_date"`
+	EndDate string `json:"end_date"`
+	Reason string `json:"reason"`
+	SourceUID string `json:"source_uid,omitempty"`
+}
+
+type AvailabilityImportItem struct {
+	SourceUID string `json:"source_uid"`
+	Attendee string `json:"attendee,omitempty"`
+	UserID string `json:"user_id,omitempty"`
+	StartDate string `json:"start_date,omitempty"`
+	EndDate string `json:"end_date,omitempty"`
+	Reason string `json:"reason,omitempty"`
+	Action string `json:"action"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type AvailabilityImportResult struct {
+	DryRun bool `json:"dry_run"`
+	Items []AvailabilityImportItem `json:"items"`
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Deleted int `json:"deleted"`
+	Unchanged int `json:"unchanged"`
+	Skipped int `json:"skipped"`
+}
+
+// OrgState is the desired state of every team and user. Users missing from
+// it are deactivated; teams missing from it are left alone.
+type OrgState struct {
+	Teams []OrgTeam `json:"teams"`
+}
+
+// OrgTeam describes a team. Settings, when present, replace the team's SLA
+// and stale policy; when absent they are left as they are.
+type OrgTeam struct {
+	TeamName string `json:"team_name"`
+	Settings *TeamSettings `json:"settings"`
+	Members []OrgMember `json:"members"`
+}
+
+// OrgMember describes a user. Members are active unless IsActive says
+// otherwise; MaxOpenReviews and ReviewWeight are only changed when given.
+type OrgMember struct {
+	UserID string `json:"user_id"`
+	Username string `json:"username"`
+	IsActive *bool `json:"is_active"`
+	MaxOpenReviews *int `json:"max_open_reviews"`
+	ReviewWeight *float64 `json:"review_weight"`
+}
+
+type TeamSettings struct {
+	TeamName string `json:"-"`
+	ReviewSLAHours *int `json:"review_sla_hours"`
+	ReassignAfterHours *int `json:"reassign_after_hours"`
+	StaleWarnAfterDays *int `json:"stale_warn_after_days"`
+	StaleCloseAfterDays *int `json:"stale_close_after_days"`
+	StaleExemptLabel string `json:"stale_exempt_label"`
+}
+
+type FieldChange struct {
+	From string `json:"from"`
+	To string `json:"to"`
+}
+
+type OrgSyncChange struct {
+	Action string `json:"action"`
+	TeamName string `json:"team_name"`
+	UserID string `json:"user_id,omitempty"`
+	PullRequestID string `json:"pull_request_id,omitempty"`
+	Changes map[string]FieldChange `json:"changes,omitempty"`
+}
+
+type OrgSyncResult struct {
+	DryRun bool `json:"dry_run"`
+	Changes []OrgSyncChange `json:"changes"`
+	Unchanged int `json:"unchanged"`
+	TopUpSkipped []TopUpSkip `json:"top_up_skipped,omitempty"`
+}
+
+type ReviewAssignment struct {
+	PullRequestID string `json:"pull_request_id"`
+	UserID string `json:"user_id"`
+	AssignedAt *time.Time `json:"assigned_at,omitempty"`
+	Verdict string `json:"verdict,omitempty"`
+}
+
+type ReviewerStats struct {
+	UserID string `json:"user_id"`
+	Username string `json:"username"`
+	TeamName string `json:"team_name"`
+	OpenReviews int `json:"open_reviews"`
+	TotalReviews int `json:"total_reviews"`
+	Declines map[string]int `json:"declines"`
+	AgeClock string `json:"age_clock"`
+	WorkSchedule *WorkSchedule `json:"work_schedule,omitempty"`
+	OldestOpenReviewAgeSeconds int64 `json:"oldest_open_review_age_seconds"`
+	AvgOpenReviewAgeSeconds int64 `json:"avg_open_review_age_seconds"`
+}
+
+// WorkSchedule describes when a user reviews. Without a TimeZone review time
+// is counted on the wall clock; otherwise only working hours on WorkDays
+// (ISO weekdays, 1 is Monday) outside the team's holidays count.
+type WorkSchedule struct {
+	UserID string `json:"user_id"`
+	TeamName string `json:"-"`
+	TimeZone string `json:"time_zone"`
+	WorkStart string `json:"work_start"`
+	WorkEnd string `json:"work_end"`
+	WorkDays []int `json:"work_days"`
+}
+
+type TeamHoliday struct {
+	TeamName string `json:"team_name"`
+	Date string `json:"date"`
+	Name string `json:"name"`
+}
+
+const (
+	ClockWall = "wall"
+	ClockBusiness = "business"
+)
+
+type UnderstaffedPR struct {
+	PullRequestID string `json:"pull_request_id"`
+	AuthorID string `json:"author_id"`
+	TeamName string `json:"team_name"`
+	Reviewers int `json:"reviewers"`
+	Required int `json:"required"`
+}
+
+// TopUpSkip is an under-staffed pull request that could not be topped up.
+type TopUpSkip struct {
+	PullRequestID string `json:"pull_request_id"`
+	Error string `json:"error"`
+}
+
+type TopUpResult struct {
+	Assigned int `json:"assigned"`
+	Skipped []TopUpSkip `json:"skipped"`
+}
+
+type PREvent struct {
+	ID int64 `json:"event_id"`
+	Type string `json:"type"`
+	PullRequestID string `json:"pull_request_id,omitempty"`
+	UserID string `json:"user_id,omitempty"`
+	OldUserID string `json:"old_user_id,omitempty"`
+	NewUserID string `json:"new_user_id,omitempty"`
+	TeamName string `json:"team_name,omitempty"`
+	Reason string `json:"reason,omitempty"`
+	Actor string `json:"actor,omitempty"`
+	Details map[string]string `json:"details,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WebhookSubscription struct {
+	ID int64 `json:"id"`
+	URL string `json:"url"`
+	Secret string `json:"secret,omitempty"`
+	EventTypes []string `json:"event_types"`
+	TeamName string `json:"team_name,omitempty"`
+	IsActive bool `json:"is_active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID int64 `json:"id"`
+	SubscriptionID int64 `json:"subscription_id"`
+	EventID int64 `json:"event_id"`
+	EventType string `json:"event_type"`
+	Status string `json:"status"`
+	Attempts int `json:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	LastStatusCode *int `json:"last_status_code,omitempty"`
+	LastError string `json:"last_error,omitempty"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WebhookDeliveryFilter struct {
+	SubscriptionID int64
+	Status string
+	AfterID int64
+	Limit int
+}
+
+const (
+	DeliveryPending = "PENDING"
+	DeliveryDelivered = "DELIVERED"
+	DeliveryDead = "DEAD"
+)
+
+// ChatSettings configure the Slack/Mattermost-compatible incoming webhook a
+// team is notified through. An empty WebhookURL turns notifications off and
+// a nil DigestAt ("HH:MM", UTC) turns the daily digest off.
+type ChatSettings struct {
+	TeamName string `json:"team_name"`
+	WebhookURL string `json:"webhook_url"`
+	Template string `json:"template"`
+	DigestAt *string `json:"digest_at"`
+}
+
+// Notification is a queued message about an event or a daily digest.
+type Notification struct {
+	ID int64 `json:"id"`
+	Channel string `json:"channel"`
+	Kind string `json:"kind"`
+	TeamName string `json:"team_name"`
+	UserID string `json:"user_id,omitempty"`
+	EventID *int64 `json:"event_id,omitempty"`
+	DigestDate *string `json:"digest_date,omitempty"`
+	Status string `json:"status"`
+	Attempts int `json:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	LastError string `json:"last_error,omitempty"`
+	SentAt *time.Time `json:"sent_at,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// EmailPreferences lists the email topics a user opted out of.
+type EmailPreferences struct {
+	UserID string `json:"user_id"`
+	OptOut []string `json:"opt_out"`
+}
+
+type NotificationFilter struct {
+	Channel string
+	Status string
+	AfterID int64
+	Limit int
+}
+
+const (
+	ChannelChat = "chat"
+	ChannelEmail = "email"
+)
+
+const (
+	EmailAssignment = "assignment"
+	EmailReassignment = "reassignment"
+	EmailSLABreach = "sla_breach"
+	EmailDigest = "digest"
+)
+
+// EmailTopics lists the kinds of email users can opt out of.
+var EmailTopics = []string{EmailAssignment, EmailReassignment, EmailSLABreach, EmailDigest}
+
+const (
+	NotificationEvent = "event"
+	NotificationDigest = "digest"
+)
+
+const (
+	NotificationPending = "PENDING"
+	NotificationSent = "SENT"
+	NotificationDead = "DEAD"
+)
+
+type EventFilter struct {
+	Type string
+	Types []string
+	PullRequestID string
+	UserID string
+	TeamName string
+	Actor string
+	Since *time.Time
+	Until *time.Time
+	AfterID int64
+	Limit int
+}
+
+// DomainError carries an error code together with a human-readable detail.
+// Error returns the code, so it compares like errors.New(code).
+type DomainError struct {
+	Code string
+	Detail string
+}
+
+func (e *DomainError) Error() string {
+	return e.Code
 }
 
 type ErrorResponse struct {
@@ -49,13 +461,109 @@ type ErrorDetail struct {
 const (
 	StatusOpen = "OPEN"
 	StatusMerged = "MERGED"
+	StatusClosed = "CLOSED"
+	StatusAll = "ALL"
+)
+
+const (
+	VerdictApproved = "APPROVED"
+	VerdictChangesRequested = "CHANGES_REQUESTED"
 )
 
 const (
 	ErrTeamExists = "TEAM_EXISTS"
 	ErrPRExists = "PR_EXISTS"
 	ErrPRMerged = "PR_MERGED"
+	ErrPRClosed = "PR_CLOSED"
 	ErrNotAssigned = "NOT_ASSIGNED"
 	ErrNoCandidate = "NO_CANDIDATE"
 	ErrNotFound = "NOT_FOUND"
+	ErrBadRequest = "BAD_REQUEST"
+	ErrUserReferenced = "USER_REFERENCED"
+	ErrUnavailable = "UNAVAILABLE"
+	ErrDeliveryNotFailed = "DELIVERY_NOT_FAILED"
+)
+
+const DateLayout = "2006-01-02"
+
+const (
+	EventPRCreated = "pr.created"
+	EventReviewerAssigned = "pr.reviewer_assigned"
+	EventReviewerReassigned = "pr.reviewer_reassigned"
+	EventReviewerDeclined = "pr.reviewer_declined"
+	EventReviewerAdded = "pr.reviewer_added"
+	EventReviewerRemoved = "pr.reviewer_removed"
+	EventReviewSubmitted = "pr.review_submitted"
+	EventSLABreached = "pr.sla_breached"
+	EventPRMerged = "pr.merged"
+	EventPRStaleWarning = "pr.stale_warning"
+	EventPRLabelsChanged = "pr.labels_changed"
+	EventPRStatusChanged = "pr.status_changed"
+	EventTeamCreated = "team.created"
+	EventTeamSLAChanged = "team.sla_changed"
+	EventTeamHolidaysChanged = "team.holidays_changed"
+	EventTeamStalePolicyChanged = "team.stale_policy_changed"
+	EventTeamChatChanged = "team.chat_changed"
+	EventUserUpserted = "user.upserted"
+	EventUserActiveChanged = "user.active_changed"
+	EventUserCapacityChanged = "user.capacity_changed"
+	EventUserWeightChanged = "user.weight_changed"
+	EventUserScheduleChanged = "user.schedule_changed"
+	EventUserLoginLinked = "user.login_linked"
+	EventUserLoginUnlinked = "user.login_unlinked"
+	EventUserChatMentionChanged = "user.chat_mention_changed"
+	EventUserEmailPreferencesChanged = "user.email_preferences_changed"
+	EventUserUpdated = "user.updated"
+	EventUserAnonymized = "user.anonymized"
+	EventUserDeleted = "user.deleted"
+)
+
+// EventTypes lists every event type, for validating subscriptions.
+var EventTypes = []string{
+	EventPRCreated, EventReviewerAssigned, EventReviewerReassigned, EventReviewerDeclined,
+	EventReviewerAdded, EventReviewerRemoved, EventReviewSubmitted, EventSLABreached,
+	EventPRMerged, EventPRStaleWarning, EventPRLabelsChanged, EventPRStatusChanged,
+	EventTeamCreated, EventTeamSLAChanged, EventTeamHolidaysChanged, EventTeamStalePolicyChanged,
+	EventTeamChatChanged, EventUserUpserted, EventUserActiveChanged, EventUserCapacityChanged,
+	EventUserWeightChanged, EventUserScheduleChanged, EventUserLoginLinked, EventUserLoginUnlinked,
+	EventUserChatMentionChanged, EventUserEmailPreferencesChanged, EventUserUpdated, EventUserAnonymized,
+	EventUserDeleted,
+}
+
+// ChatEventTypes are the events teams are notified about in chat.
+var ChatEventTypes = []string{EventReviewerAssigned, EventReviewerAdded, EventReviewerReassigned}
+
+// StreamEventTypes are the events pushed by the live event stream.
+var StreamEventTypes = []string{
+	EventPRCreated, EventReviewerAssigned, EventReviewerAdded, EventReviewerReassigned, EventPRMerged,
+}
+
+const ActorSystem = "system"
+
+const AnonymizedUsername = "deleted user"
+
+const (
+	DeclineBusy = "BUSY"
+	DeclineConflictOfInterest = "CONFLICT_OF_INTEREST"
+	DeclineLacksContext = "LACKS_CONTEXT"
+)
+
+var DeclineReasons = []string{DeclineBusy, DeclineConflictOfInterest, DeclineLacksContext}
+
+const (
+	ImportActionCreate = "create"
+	ImportActionUpdate = "update"
+	ImportActionDelete = "delete"
+	ImportActionUnchanged = "unchanged"
+	ImportActionSkip = "skip"
+)
+
+const (
+	SyncActionCreateTeam = "create_team"
+	SyncActionUpdateTeam = "update_team"
+	SyncActionCreateUser = "create_user"
+	SyncActionUpdateUser = "update_user"
+	SyncActionMoveUser = "move_user"
+	SyncActionDeactivateUser = "deactivate_user"
+	SyncActionReleaseReview = "release_review"
 )
\ No newline at end of file
@@ -0,0 +1,63 @@
+package gql
+
+import (
+	"errors"
+	"log"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+// errorMessages are used for errors that carry no detail of their own.
+var errorMessages = map[string]string{
+	entities.ErrBadRequest:        "invalid request",
+	entities.ErrNotFound:          "resource not found",
+	entities.ErrTeamExists:        "team_name already exists",
+	entities.ErrPRExists:          "PR id already exists",
+	entities.ErrPRMerged:          "PR is merged",
+	entities.ErrPRClosed:          "PR is closed",
+	entities.ErrNotAssigned:       "reviewer is not assigned to this PR",
+	entities.ErrNoCandidate:       "no active replacement candidate in team",
+	entities.ErrUserReferenced:    "user is referenced by pull requests",
+	entities.ErrUnavailable:       "service unavailable",
+	entities.ErrDeliveryNotFailed: "only failed deliveries can be redelivered",
+}
+
+// serviceError is reported in the GraphQL errors list with the service error
+// code under extensions.code.
+type serviceError struct {
+	code    string
+	message string
+}
+
+func (e *serviceError) Error() string {
+	return e.message
+}
+
+func (e *serviceError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.code}
+}
+
+// toError turns a service error into a serviceError. Unknown errors are
+// logged and reported as INTERNAL_ERROR.
+func toError(err error) error {
+	code := err.Error()
+	message, ok := errorMessages[code]
+	if !ok {
+		log.Printf("Error handling GraphQL request: %v", err)
+		return &serviceError{code: "INTERNAL_ERROR", message: "Internal server error"}
+	}
+
+	var domainErr *entities.DomainError
+	if errors.As(err, &domainErr) && domainErr.Detail != "" {
+		message = domainErr.Detail
+	}
+	return &serviceError{code: code, message: message}
+}
+
+func notFound(message string) error {
+	return &serviceError{code: entities.ErrNotFound, message: message}
+}
+
+func badRequest(message string) error {
+	return &serviceError{code: entities.ErrBadRequest, message: message}
+}
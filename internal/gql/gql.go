@@ -0,0 +1,42 @@
+// Package gql serves a GraphQL API for dashboards on top of service.Service.
+// Nested fields are resolved through per-request batch loaders, so a query
+// costs one database round trip per level rather than one per object.
+package gql
+
+import (
+	"context"
+	_ "embed"
+
+	graphql "github.com/graph-gophers/graphql-go"
+
+	"github.com/alexalexbor04/pull_request_service/internal/service"
+)
+
+const (
+	maxDepth = 10
+	// maxParallelism bounds the resolvers running at once and with it the
+	// size of the batches the loaders can collect.
+	maxParallelism = 100
+)
+
+//go:embed schema.graphql
+var schemaSource string
+
+type Schema struct {
+	schema  *graphql.Schema
+	service *service.Service
+}
+
+func NewSchema(svc *service.Service) *Schema {
+	schema := graphql.MustParseSchema(schemaSource, &resolver{service: svc},
+		graphql.MaxDepth(maxDepth),
+		graphql.MaxParallelism(maxParallelism),
+	)
+	return &Schema{schema: schema, service: svc}
+}
+
+// Exec runs a query or mutation on behalf of actor, who is recorded in the
+// audit log for mutations.
+func (s *Schema) Exec(ctx context.Context, actor, query, operationName string, variables map[string]interface{}) *graphql.Response {
+	return s.schema.Exec(withRequest(ctx, s.service, actor), query, operationName, variables)
+}
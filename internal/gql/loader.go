@@ -0,0 +1,191 @@
+package gql
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+	"github.com/alexalexbor04/pull_request_service/internal/service"
+)
+
+const (
+	// loaderWait is how long a batch collects keys from resolvers running
+	// in parallel before it is fetched.
+	loaderWait     = 2 * time.Millisecond
+	loaderMaxBatch = 500
+)
+
+// loader batches the keys requested by concurrently running resolvers into a
+// single fetch. A batch is fetched loaderWait after its first key or as soon
+// as it holds loaderMaxBatch keys. Results, errors included, are kept for the
+// rest of the request.
+type loader[K comparable, V any] struct {
+	fetch func(keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	pending *batch[K, V]
+	batches map[K]*batch[K, V]
+}
+
+type batch[K comparable, V any] struct {
+	keys   []K
+	once   sync.Once
+	done   chan struct{}
+	values map[K]V
+	err    error
+}
+
+func newLoader[K comparable, V any](fetch func(keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{fetch: fetch, batches: make(map[K]*batch[K, V])}
+}
+
+// load returns the value for key and whether the fetch found one.
+func (l *loader[K, V]) load(key K) (V, bool, error) {
+	l.mu.Lock()
+	b, ok := l.batches[key]
+	if !ok {
+		b = l.pending
+		if b == nil {
+			b = &batch[K, V]{done: make(chan struct{})}
+			l.pending = b
+			time.AfterFunc(loaderWait, func() { l.dispatch(b) })
+		}
+		b.keys = append(b.keys, key)
+		l.batches[key] = b
+		if len(b.keys) >= loaderMaxBatch {
+			l.pending = nil
+			go l.dispatch(b)
+		}
+	}
+	l.mu.Unlock()
+
+	<-b.done
+	value, found := b.values[key]
+	return value, found, b.err
+}
+
+func (l *loader[K, V]) loadMany(keys []K) ([]V, error) {
+	type result struct {
+		value V
+		found bool
+		err   error
+	}
+	results := make([]result, len(keys))
+
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := &results[i]
+			r.value, r.found, r.err = l.load(key)
+		}()
+	}
+	wg.Wait()
+
+	values := make([]V, 0, len(keys))
+	for _, r := range results {
+		if r.err != nil {
+			return nil, r.err
+		}
+		if r.found {
+			values = append(values, r.value)
+		}
+	}
+	return values, nil
+}
+
+func (l *loader[K, V]) dispatch(b *batch[K, V]) {
+	l.mu.Lock()
+	if l.pending == b {
+		l.pending = nil
+	}
+	l.mu.Unlock()
+
+	b.once.Do(func() {
+		b.values, b.err = l.fetch(b.keys)
+		close(b.done)
+	})
+}
+
+// loaders are created per request so that nothing is cached across
+// requests.
+type loaders struct {
+	service *service.Service
+
+	users         *loader[string, entities.User]
+	members       *loader[string, []entities.User]
+	pullRequests  *loader[string, entities.PullRequest]
+	prAssignments *loader[string, []entities.ReviewAssignment]
+
+	mu              sync.Mutex
+	userAssignments map[string]*loader[string, []entities.ReviewAssignment]
+}
+
+func newLoaders(svc *service.Service) *loaders {
+	return &loaders{
+		service:         svc,
+		users:           newLoader(svc.GetUsersByIDs),
+		members:         newLoader(svc.GetMembersOfTeams),
+		pullRequests:    newLoader(svc.GetPullRequestsByIDs),
+		prAssignments:   newLoader(svc.GetAssignmentsForPRs),
+		userAssignments: make(map[string]*loader[string, []entities.ReviewAssignment]),
+	}
+}
+
+// reviews returns the loader of review assignments by reviewer for pull
+// requests with the given status.
+func (l *loaders) reviews(status string) *loader[string, []entities.ReviewAssignment] {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ld, ok := l.userAssignments[status]
+	if !ok {
+		ld = newLoader(func(userIDs []string) (map[string][]entities.ReviewAssignment, error) {
+			return l.service.GetAssignmentsForUsers(userIDs, status)
+		})
+		l.userAssignments[status] = ld
+	}
+	return ld
+}
+
+// request holds the loaders of one GraphQL request. A mutation drops them
+// once it has changed data, so that nothing loaded before it is served
+// afterwards.
+type request struct {
+	service *service.Service
+	actor   string
+
+	mu      sync.Mutex
+	loaders *loaders
+}
+
+type contextKey struct{}
+
+func withRequest(ctx context.Context, svc *service.Service, actor string) context.Context {
+	return context.WithValue(ctx, contextKey{}, &request{service: svc, actor: actor, loaders: newLoaders(svc)})
+}
+
+func requestFrom(ctx context.Context) *request {
+	return ctx.Value(contextKey{}).(*request)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	req := requestFrom(ctx)
+	req.mu.Lock()
+	defer req.mu.Unlock()
+	return req.loaders
+}
+
+func resetLoaders(ctx context.Context) {
+	req := requestFrom(ctx)
+	req.mu.Lock()
+	defer req.mu.Unlock()
+	req.loaders = newLoaders(req.service)
+}
+
+// actorFrom identifies who performed a mutation for the audit log.
+func actorFrom(ctx context.Context) string {
+	return requestFrom(ctx).actor
+}
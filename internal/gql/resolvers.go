@@ -0,0 +1,514 @@
+package gql
+
+import (
+	"context"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+	"github.com/alexalexbor04/pull_request_service/internal/service"
+)
+
+type resolver struct {
+	service *service.Service
+}
+
+func (r *resolver) Team(ctx context.Context, args struct{ Name string }) (*teamResolver, error) {
+	team, err := r.service.GetTeam(args.Name)
+	if err != nil {
+		if err.Error() == entities.ErrNotFound {
+			return nil, nil
+		}
+		return nil, toError(err)
+	}
+	return &teamResolver{name: team.TeamName}, nil
+}
+
+func (r *resolver) User(ctx context.Context, args struct{ ID graphql.ID }) (*userResolver, error) {
+	user, found, err := loadersFrom(ctx).users.load(string(args.ID))
+	if err != nil {
+		return nil, toError(err)
+	}
+	if !found {
+		return nil, nil
+	}
+	return &userResolver{user: user}, nil
+}
+
+func (r *resolver) Users(ctx context.Context, args struct {
+	TeamName *string
+	IsActive *bool
+	First    *int32
+	After    *string
+}) (*userConnection, error) {
+	filter := entities.UserFilter{
+		TeamName: deref(args.TeamName),
+		IsActive: args.IsActive,
+		AfterID:  deref(args.After),
+	}
+	if args.First != nil {
+		filter.Limit = int(*args.First)
+	}
+
+	users, nextCursor, err := r.service.ListUsers(filter)
+	if err != nil {
+		return nil, toError(err)
+	}
+
+	conn := &userConnection{pageInfo: pageInfo{endCursor: nextCursor}}
+	for _, u := range users {
+		conn.nodes = append(conn.nodes, &userResolver{user: u})
+	}
+	return conn, nil
+}
+
+func (r *resolver) PullRequest(ctx context.Context, args struct{ ID graphql.ID }) (*pullRequestResolver, error) {
+	pr, found, err := loadersFrom(ctx).pullRequests.load(string(args.ID))
+	if err != nil {
+		return nil, toError(err)
+	}
+	if !found {
+		return nil, nil
+	}
+	return &pullRequestResolver{pr: pr}, nil
+}
+
+type pullRequestFilter struct {
+	Status      *string
+	AuthorID    *graphql.ID
+	ReviewerID  *graphql.ID
+	TeamName    *string
+	Name        *string
+	CreatedFrom *graphql.Time
+	CreatedTo   *graphql.Time
+	MergedFrom  *graphql.Time
+	MergedTo    *graphql.Time
+	Ascending   *bool
+}
+
+func (r *resolver) PullRequests(ctx context.Context, args struct {
+	Filter *pullRequestFilter
+	First  *int32
+	After  *string
+}) (*pullRequestConnection, error) {
+	var filter entities.PRFilter
+	if f := args.Filter; f != nil {
+		filter = entities.PRFilter{
+			Status:       deref(f.Status),
+			AuthorID:     derefID(f.AuthorID),
+			ReviewerID:   derefID(f.ReviewerID),
+			TeamName:     deref(f.TeamName),
+			NameContains: deref(f.Name),
+			CreatedFrom:  fromTime(f.CreatedFrom),
+			CreatedTo:    fromTime(f.CreatedTo),
+			MergedFrom:   fromTime(f.MergedFrom),
+			MergedTo:     fromTime(f.MergedTo),
+			Ascending:    f.Ascending != nil && *f.Ascending,
+		}
+	}
+	if args.First != nil {
+		filter.Limit = int(*args.First)
+	}
+
+	prs, nextCursor, err := r.service.ListPullRequests(filter, deref(args.After))
+	if err != nil {
+		return nil, toError(err)
+	}
+
+	conn := &pullRequestConnection{pageInfo: pageInfo{endCursor: nextCursor}}
+	for _, pr := range prs {
+		conn.nodes = append(conn.nodes, &pullRequestResolver{pr: pr})
+	}
+	return conn, nil
+}
+
+type teamMemberInput struct {
+	UserID   graphql.ID
+	Username string
+	IsActive bool
+}
+
+func (r *resolver) CreateTeam(ctx context.Context, args struct {
+	Input struct {
+		Name    string
+		Members []teamMemberInput
+	}
+}) (*teamResolver, error) {
+	if args.Input.Name == "" {
+		return nil, badRequest("name is required")
+	}
+
+	team := &entities.Team{TeamName: args.Input.Name, Members: []entities.TeamMember{}}
+	for _, m := range args.Input.Members {
+		team.Members = append(team.Members, entities.TeamMember{UserID: string(m.UserID), Username: m.Username, IsActive: m.IsActive})
+	}
+	if err := r.service.CreateTeam(team); err != nil {
+		return nil, toError(err)
+	}
+
+	resetLoaders(ctx)
+	return &teamResolver{name: team.TeamName}, nil
+}
+
+func (r *resolver) SetUserActive(ctx context.Context, args struct {
+	UserID   graphql.ID
+	IsActive bool
+}) (*userResolver, error) {
+	user, err := r.service.SetUserActive(string(args.UserID), args.IsActive)
+	if err != nil {
+		return nil, toError(err)
+	}
+
+	resetLoaders(ctx)
+	return &userResolver{user: *user}, nil
+}
+
+func (r *resolver) CreatePullRequest(ctx context.Context, args struct {
+	ID       graphql.ID
+	Name     string
+	AuthorID graphql.ID
+}) (*pullRequestResolver, error) {
+	pr, err := r.service.CreatePullRequest(string(args.ID), args.Name, string(args.AuthorID))
+	return r.changed(ctx, pr, err)
+}
+
+func (r *resolver) MergePullRequest(ctx context.Context, args struct{ ID graphql.ID }) (*pullRequestResolver, error) {
+	pr, err := r.service.MergePullRequest(string(args.ID))
+	return r.changed(ctx, pr, err)
+}
+
+func (r *resolver) ReassignReviewer(ctx context.Context, args struct {
+	PullRequestID graphql.ID
+	OldUserID     graphql.ID
+	Reason        string
+}) (*reassignmentResolver, error) {
+	pr, replacedBy, err := r.service.ReassignReviewer(string(args.PullRequestID), string(args.OldUserID), args.Reason, actorFrom(ctx))
+	if err != nil {
+		return nil, toError(err)
+	}
+
+	resetLoaders(ctx)
+	return &reassignmentResolver{pr: *pr, replacedBy: replacedBy}, nil
+}
+
+func (r *resolver) AddReviewer(ctx context.Context, args struct {
+	PullRequestID graphql.ID
+	UserID        graphql.ID
+}) (*pullRequestResolver, error) {
+	pr, err := r.service.AddReviewer(string(args.PullRequestID), string(args.UserID), actorFrom(ctx))
+	return r.changed(ctx, pr, err)
+}
+
+func (r *resolver) RemoveReviewer(ctx context.Context, args struct {
+	PullRequestID graphql.ID
+	UserID        graphql.ID
+}) (*pullRequestResolver, error) {
+	pr, err := r.service.RemoveReviewer(string(args.PullRequestID), string(args.UserID), actorFrom(ctx))
+	return r.changed(ctx, pr, err)
+}
+
+func (r *resolver) DeclineReview(ctx context.Context, args struct {
+	PullRequestID graphql.ID
+	UserID        graphql.ID
+	Reason        string
+	Comment       *string
+}) (*reassignmentResolver, error) {
+	pr, replacedBy, err := r.service.DeclineReview(string(args.PullRequestID), string(args.UserID), args.Reason, deref(args.Comment))
+	if err != nil {
+		return nil, toError(err)
+	}
+
+	resetLoaders(ctx)
+	return &reassignmentResolver{pr: *pr, replacedBy: replacedBy}, nil
+}
+
+func (r *resolver) SubmitReview(ctx context.Context, args struct {
+	PullRequestID graphql.ID
+	UserID        graphql.ID
+	Verdict       string
+}) (*pullRequestResolver, error) {
+	pr, err := r.service.SubmitReview(string(args.PullRequestID), string(args.UserID), args.Verdict)
+	return r.changed(ctx, pr, err)
+}
+
+// changed finishes a mutation that returns the pull request it modified.
+func (r *resolver) changed(ctx context.Context, pr *entities.PullRequest, err error) (*pullRequestResolver, error) {
+	if err != nil {
+		return nil, toError(err)
+	}
+
+	resetLoaders(ctx)
+	return &pullRequestResolver{pr: *pr}, nil
+}
+
+type teamResolver struct {
+	name string
+}
+
+func (t *teamResolver) Name() string {
+	return t.name
+}
+
+func (t *teamResolver) Members(ctx context.Context, args struct{ IsActive *bool }) ([]*userResolver, error) {
+	members, _, err := loadersFrom(ctx).members.load(t.name)
+	if err != nil {
+		return nil, toError(err)
+	}
+
+	result := []*userResolver{}
+	for _, m := range members {
+		if args.IsActive == nil || m.IsActive == *args.IsActive {
+			result = append(result, &userResolver{user: m})
+		}
+	}
+	return result, nil
+}
+
+type userResolver struct {
+	user entities.User
+}
+
+func (u *userResolver) ID() graphql.ID {
+	return graphql.ID(u.user.ID)
+}
+
+func (u *userResolver) Username() string {
+	return u.user.Username
+}
+
+func (u *userResolver) TeamName() string {
+	return u.user.TeamName
+}
+
+func (u *userResolver) Team() *teamResolver {
+	return &teamResolver{name: u.user.TeamName}
+}
+
+func (u *userResolver) IsActive() bool {
+	return u.user.IsActive
+}
+
+func (u *userResolver) MaxOpenReviews() *int32 {
+	if u.user.MaxOpenReviews == nil {
+		return nil
+	}
+	limit := int32(*u.user.MaxOpenReviews)
+	return &limit
+}
+
+func (u *userResolver) ReviewWeight() float64 {
+	return u.user.ReviewWeight
+}
+
+func (u *userResolver) Email() *string {
+	if u.user.Email == "" {
+		return nil
+	}
+	return &u.user.Email
+}
+
+func (u *userResolver) Reviews(ctx context.Context, args struct{ Status string }) ([]*assignmentResolver, error) {
+	assignments, _, err := loadersFrom(ctx).reviews(args.Status).load(u.user.ID)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return toAssignmentResolvers(assignments), nil
+}
+
+type pullRequestResolver struct {
+	pr entities.PullRequest
+}
+
+func (p *pullRequestResolver) ID() graphql.ID {
+	return graphql.ID(p.pr.ID)
+}
+
+func (p *pullRequestResolver) Name() string {
+	return p.pr.Name
+}
+
+func (p *pullRequestResolver) Status() string {
+	return p.pr.Status
+}
+
+func (p *pullRequestResolver) Author(ctx context.Context) (*userResolver, error) {
+	return loadUser(ctx, p.pr.AuthorID)
+}
+
+func (p *pullRequestResolver) Reviewers(ctx context.Context) ([]*userResolver, error) {
+	users, err := loadersFrom(ctx).users.loadMany(p.pr.AssignedReviewers)
+	if err != nil {
+		return nil, toError(err)
+	}
+
+	result := make([]*userResolver, len(users))
+	for i, u := range users {
+		result[i] = &userResolver{user: u}
+	}
+	return result, nil
+}
+
+func (p *pullRequestResolver) Assignments(ctx context.Context) ([]*assignmentResolver, error) {
+	assignments, _, err := loadersFrom(ctx).prAssignments.load(p.pr.ID)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return toAssignmentResolvers(assignments), nil
+}
+
+func (p *pullRequestResolver) Labels() []string {
+	if p.pr.Labels == nil {
+		return []string{}
+	}
+	return p.pr.Labels
+}
+
+func (p *pullRequestResolver) CreatedAt() *graphql.Time {
+	return toTime(p.pr.CreatedAt)
+}
+
+func (p *pullRequestResolver) MergedAt() *graphql.Time {
+	return toTime(p.pr.MergedAt)
+}
+
+func (p *pullRequestResolver) ClosedAt() *graphql.Time {
+	return toTime(p.pr.ClosedAt)
+}
+
+type assignmentResolver struct {
+	assignment entities.ReviewAssignment
+}
+
+func toAssignmentResolvers(assignments []entities.ReviewAssignment) []*assignmentResolver {
+	result := make([]*assignmentResolver, len(assignments))
+	for i, a := range assignments {
+		result[i] = &assignmentResolver{assignment: a}
+	}
+	return result
+}
+
+func (a *assignmentResolver) PullRequest(ctx context.Context) (*pullRequestResolver, error) {
+	pr, found, err := loadersFrom(ctx).pullRequests.load(a.assignment.PullRequestID)
+	if err != nil {
+		return nil, toError(err)
+	}
+	if !found {
+		return nil, notFound("pull request not found")
+	}
+	return &pullRequestResolver{pr: pr}, nil
+}
+
+func (a *assignmentResolver) Reviewer(ctx context.Context) (*userResolver, error) {
+	return loadUser(ctx, a.assignment.UserID)
+}
+
+func (a *assignmentResolver) AssignedAt() *graphql.Time {
+	return toTime(a.assignment.AssignedAt)
+}
+
+func (a *assignmentResolver) Verdict() *string {
+	if a.assignment.Verdict == "" {
+		return nil
+	}
+	return &a.assignment.Verdict
+}
+
+type reassignmentResolver struct {
+	pr         entities.PullRequest
+	replacedBy string
+}
+
+func (r *reassignmentResolver) PullRequest() *pullRequestResolver {
+	return &pullRequestResolver{pr: r.pr}
+}
+
+func (r *reassignmentResolver) ReplacedBy(ctx context.Context) (*userResolver, error) {
+	return loadUser(ctx, r.replacedBy)
+}
+
+type pageInfo struct {
+	endCursor string
+}
+
+func (p pageInfo) EndCursor() *string {
+	if p.endCursor == "" {
+		return nil
+	}
+	return &p.endCursor
+}
+
+func (p pageInfo) HasNextPage() bool {
+	return p.endCursor != ""
+}
+
+type userConnection struct {
+	nodes    []*userResolver
+	pageInfo pageInfo
+}
+
+func (c *userConnection) Nodes() []*userResolver {
+	if c.nodes == nil {
+		return []*userResolver{}
+	}
+	return c.nodes
+}
+
+func (c *userConnection) PageInfo() pageInfo {
+	return c.pageInfo
+}
+
+type pullRequestConnection struct {
+	nodes    []*pullRequestResolver
+	pageInfo pageInfo
+}
+
+func (c *pullRequestConnection) Nodes() []*pullRequestResolver {
+	if c.nodes == nil {
+		return []*pullRequestResolver{}
+	}
+	return c.nodes
+}
+
+func (c *pullRequestConnection) PageInfo() pageInfo {
+	return c.pageInfo
+}
+
+func loadUser(ctx context.Context, id string) (*userResolver, error) {
+	user, found, err := loadersFrom(ctx).users.load(id)
+	if err != nil {
+		return nil, toError(err)
+	}
+	if !found {
+		return nil, notFound("user not found")
+	}
+	return &userResolver{user: user}, nil
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func derefID(id *graphql.ID) string {
+	if id == nil {
+		return ""
+	}
+	return string(*id)
+}
+
+func toTime(t *time.Time) *graphql.Time {
+	if t == nil {
+		return nil
+	}
+	return &graphql.Time{Time: *t}
+}
+
+func fromTime(t *graphql.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	return &t.Time
+}
@@ -0,0 +1,88 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+const errorDomain = "pr-reviewer-service"
+
+// errorCodes maps service error codes to gRPC status codes.
+var errorCodes = map[string]codes.Code{
+	entities.ErrBadRequest:        codes.InvalidArgument,
+	entities.ErrNotFound:          codes.NotFound,
+	entities.ErrTeamExists:        codes.AlreadyExists,
+	entities.ErrPRExists:          codes.AlreadyExists,
+	entities.ErrPRMerged:          codes.FailedPrecondition,
+	entities.ErrPRClosed:          codes.FailedPrecondition,
+	entities.ErrNotAssigned:       codes.FailedPrecondition,
+	entities.ErrNoCandidate:       codes.FailedPrecondition,
+	entities.ErrUserReferenced:    codes.FailedPrecondition,
+	entities.ErrUnavailable:       codes.Unavailable,
+	entities.ErrDeliveryNotFailed: codes.FailedPrecondition,
+}
+
+// errorMessages are used for errors that carry no detail of their own.
+var errorMessages = map[string]string{
+	entities.ErrBadRequest:        "invalid request",
+	entities.ErrNotFound:          "resource not found",
+	entities.ErrTeamExists:        "team_name already exists",
+	entities.ErrPRExists:          "PR id already exists",
+	entities.ErrPRMerged:          "PR is merged",
+	entities.ErrPRClosed:          "PR is closed",
+	entities.ErrNotAssigned:       "reviewer is not assigned to this PR",
+	entities.ErrNoCandidate:       "no active replacement candidate in team",
+	entities.ErrUserReferenced:    "user is referenced by pull requests",
+	entities.ErrUnavailable:       "service unavailable",
+	entities.ErrDeliveryNotFailed: "only failed deliveries can be redelivered",
+}
+
+func invalidArgument(message string) error {
+	return errorStatus(entities.ErrBadRequest, message)
+}
+
+func errorStatus(code, message string) error {
+	st := status.New(errorCodes[code], message)
+	if detailed, err := st.WithDetails(&errdetails.ErrorInfo{Reason: code, Domain: errorDomain}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// toStatus turns a service error into a gRPC status error with an ErrorInfo
+// whose reason is the service error code. Unknown errors are logged and
+// reported as Internal.
+func toStatus(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	code := err.Error()
+	if _, ok := errorCodes[code]; !ok {
+		log.Printf("Error handling gRPC request: %v", err)
+		return status.Error(codes.Internal, "Internal server error")
+	}
+
+	message := errorMessages[code]
+	var domainErr *entities.DomainError
+	if errors.As(err, &domainErr) && domainErr.Detail != "" {
+		message = domainErr.Detail
+	}
+	return errorStatus(code, message)
+}
+
+func errorInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,287 @@
+// Package grpcapi serves the PRService gRPC API on top of service.Service.
+package grpcapi
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+	"github.com/alexalexbor04/pull_request_service/internal/grpcapi/pb"
+	"github.com/alexalexbor04/pull_request_service/internal/service"
+)
+
+type Server struct {
+	pb.UnimplementedPRServiceServer
+	service *service.Service
+}
+
+// NewServer returns a gRPC server with PRService and the reflection service
+// registered.
+func NewServer(svc *service.Service) *grpc.Server {
+	s := grpc.NewServer(grpc.UnaryInterceptor(errorInterceptor))
+	pb.RegisterPRServiceServer(s, &Server{service: svc})
+	reflection.Register(s)
+	return s
+}
+
+// actorFrom identifies who performed a mutation for the audit log, like the
+// X-Actor header does over HTTP.
+func actorFrom(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("x-actor"); len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+func (s *Server) AddTeam(ctx context.Context, req *pb.Team) (*pb.Team, error) {
+	if req.TeamName == "" {
+		return nil, invalidArgument("team_name is required")
+	}
+
+	team := &entities.Team{TeamName: req.TeamName}
+	for _, m := range req.Members {
+		team.Members = append(team.Members, entities.TeamMember{UserID: m.UserId, Username: m.Username, IsActive: m.IsActive})
+	}
+	if err := s.service.CreateTeam(team); err != nil {
+		return nil, err
+	}
+
+	created, err := s.service.GetTeam(req.TeamName)
+	if err != nil {
+		return nil, err
+	}
+	return toPBTeam(created), nil
+}
+
+func (s *Server) GetTeam(ctx context.Context, req *pb.GetTeamRequest) (*pb.Team, error) {
+	if req.TeamName == "" {
+		return nil, invalidArgument("team_name is required")
+	}
+
+	team, err := s.service.GetTeam(req.TeamName)
+	if err != nil {
+		return nil, err
+	}
+	return toPBTeam(team), nil
+}
+
+func (s *Server) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.User, error) {
+	user, err := s.service.GetUser(req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	return toPBUser(user), nil
+}
+
+func (s *Server) ListUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+	users, nextCursor, err := s.service.ListUsers(entities.UserFilter{
+		TeamName: req.TeamName,
+		IsActive: req.IsActive,
+		AfterID:  req.Cursor,
+		Limit:    int(req.Limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.ListUsersResponse{NextCursor: nextCursor}
+	for i := range users {
+		resp.Users = append(resp.Users, toPBUser(&users[i]))
+	}
+	return resp, nil
+}
+
+func (s *Server) SetUserActive(ctx context.Context, req *pb.SetUserActiveRequest) (*pb.User, error) {
+	user, err := s.service.SetUserActive(req.UserId, req.IsActive)
+	if err != nil {
+		return nil, err
+	}
+	return toPBUser(user), nil
+}
+
+func (s *Server) GetUserReviews(ctx context.Context, req *pb.GetUserReviewsRequest) (*pb.GetUserReviewsResponse, error) {
+	if req.UserId == "" {
+		return nil, invalidArgument("user_id is required")
+	}
+
+	filter := entities.ReviewQueueFilter{Status: req.Status, Limit: int(req.Limit)}
+	prs, nextCursor, err := s.service.GetUserReviews(req.UserId, filter, req.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.GetUserReviewsResponse{UserId: req.UserId, NextCursor: nextCursor}
+	for _, pr := range prs {
+		resp.PullRequests = append(resp.PullRequests, &pb.PullRequestShort{
+			PullRequestId:   pr.ID,
+			PullRequestName: pr.Name,
+			AuthorId:        pr.AuthorID,
+			Status:          pr.Status,
+			CreatedAt:       toTimestamp(pr.CreatedAt),
+			AssignedAt:      toTimestamp(pr.AssignedAt),
+			AgeSeconds:      pr.AgeSeconds,
+			Verdict:         pr.Verdict,
+			HasVerdict:      pr.HasVerdict,
+		})
+	}
+	return resp, nil
+}
+
+func (s *Server) CreatePullRequest(ctx context.Context, req *pb.CreatePullRequestRequest) (*pb.PullRequest, error) {
+	if req.AuthorId == "" {
+		return nil, invalidArgument("author_id is required")
+	}
+
+	pr, err := s.service.CreatePullRequest(req.PullRequestId, req.PullRequestName, req.AuthorId)
+	if err != nil {
+		return nil, err
+	}
+	return toPBPullRequest(pr), nil
+}
+
+func (s *Server) GetPullRequest(ctx context.Context, req *pb.GetPullRequestRequest) (*pb.PullRequest, error) {
+	if req.PullRequestId == "" {
+		return nil, invalidArgument("pull_request_id is required")
+	}
+
+	pr, err := s.service.GetPullRequest(req.PullRequestId)
+	if err != nil {
+		return nil, err
+	}
+	return toPBPullRequest(pr), nil
+}
+
+func (s *Server) ListPullRequests(ctx context.Context, req *pb.ListPullRequestsRequest) (*pb.ListPullRequestsResponse, error) {
+	filter := entities.PRFilter{
+		Status:       req.Status,
+		AuthorID:     req.AuthorId,
+		ReviewerID:   req.ReviewerId,
+		TeamName:     req.TeamName,
+		NameContains: req.Name,
+		CreatedFrom:  fromTimestamp(req.CreatedFrom),
+		CreatedTo:    fromTimestamp(req.CreatedTo),
+		MergedFrom:   fromTimestamp(req.MergedFrom),
+		MergedTo:     fromTimestamp(req.MergedTo),
+		Ascending:    req.Ascending,
+		Limit:        int(req.Limit),
+	}
+
+	prs, nextCursor, err := s.service.ListPullRequests(filter, req.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.ListPullRequestsResponse{NextCursor: nextCursor}
+	for i := range prs {
+		resp.PullRequests = append(resp.PullRequests, toPBPullRequest(&prs[i]))
+	}
+	return resp, nil
+}
+
+func (s *Server) MergePullRequest(ctx context.Context, req *pb.MergePullRequestRequest) (*pb.PullRequest, error) {
+	pr, err := s.service.MergePullRequest(req.PullRequestId)
+	if err != nil {
+		return nil, err
+	}
+	return toPBPullRequest(pr), nil
+}
+
+func (s *Server) ReassignReviewer(ctx context.Context, req *pb.ReassignReviewerRequest) (*pb.ReassignReviewerResponse, error) {
+	pr, replacedBy, err := s.service.ReassignReviewer(req.PullRequestId, req.OldUserId, req.Reason, actorFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ReassignReviewerResponse{Pr: toPBPullRequest(pr), ReplacedBy: replacedBy}, nil
+}
+
+func (s *Server) AddReviewer(ctx context.Context, req *pb.AddReviewerRequest) (*pb.PullRequest, error) {
+	pr, err := s.service.AddReviewer(req.PullRequestId, req.UserId, actorFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return toPBPullRequest(pr), nil
+}
+
+func (s *Server) RemoveReviewer(ctx context.Context, req *pb.RemoveReviewerRequest) (*pb.PullRequest, error) {
+	pr, err := s.service.RemoveReviewer(req.PullRequestId, req.UserId, actorFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return toPBPullRequest(pr), nil
+}
+
+func (s *Server) DeclineReview(ctx context.Context, req *pb.DeclineReviewRequest) (*pb.DeclineReviewResponse, error) {
+	pr, replacedBy, err := s.service.DeclineReview(req.PullRequestId, req.UserId, req.Reason, req.Comment)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.DeclineReviewResponse{Pr: toPBPullRequest(pr), ReplacedBy: replacedBy}, nil
+}
+
+func (s *Server) SubmitReview(ctx context.Context, req *pb.SubmitReviewRequest) (*pb.PullRequest, error) {
+	pr, err := s.service.SubmitReview(req.PullRequestId, req.UserId, req.Verdict)
+	if err != nil {
+		return nil, err
+	}
+	return toPBPullRequest(pr), nil
+}
+
+func toPBTeam(team *entities.Team) *pb.Team {
+	result := &pb.Team{TeamName: team.TeamName}
+	for _, m := range team.Members {
+		result.Members = append(result.Members, &pb.TeamMember{UserId: m.UserID, Username: m.Username, IsActive: m.IsActive})
+	}
+	return result
+}
+
+func toPBUser(user *entities.User) *pb.User {
+	result := &pb.User{
+		UserId:       user.ID,
+		Username:     user.Username,
+		TeamName:     user.TeamName,
+		IsActive:     user.IsActive,
+		ReviewWeight: user.ReviewWeight,
+		Email:        user.Email,
+	}
+	if user.MaxOpenReviews != nil {
+		limit := int32(*user.MaxOpenReviews)
+		result.MaxOpenReviews = &limit
+	}
+	return result
+}
+
+func toPBPullRequest(pr *entities.PullRequest) *pb.PullRequest {
+	return &pb.PullRequest{
+		PullRequestId:     pr.ID,
+		PullRequestName:   pr.Name,
+		AuthorId:          pr.AuthorID,
+		Status:            pr.Status,
+		AssignedReviewers: pr.AssignedReviewers,
+		CreatedAt:         toTimestamp(pr.CreatedAt),
+		MergedAt:          toTimestamp(pr.MergedAt),
+		ClosedAt:          toTimestamp(pr.ClosedAt),
+		Labels:            pr.Labels,
+	}
+}
+
+func toTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+func fromTimestamp(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
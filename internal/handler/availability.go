@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+func (h *Handler) AddAvailability(w http.ResponseWriter, r *http.Request) {
+	var req entities.Availability
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid request body")
+		return
+	}
+
+	availability, err := h.service.AddAvailability(&req)
+	if err != nil {
+		if err.Error() == entities.ErrBadRequest {
+			writeError(w, http.StatusBadRequest, entities.ErrBadRequest, "start_date and end_date must be YYYY-MM-DD and ordered")
+			return
+		}
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "user not found")
+			return
+		}
+		log.Printf("Error adding availability: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"availability": availability,
+	})
+}
+
+func (h *Handler) GetUserAvailability(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "user_id is required")
+		return
+	}
+
+	windows, err := h.service.GetUserAvailability(userID)
+	if err != nil {
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "user not found")
+			return
+		}
+		log.Printf("Error getting availability: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	if windows == nil {
+		windows = []entities.Availability{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"user_id":      userID,
+		"availability": windows,
+	})
+}
+
+func (h *Handler) UpdateAvailability(w http.ResponseWriter, r *http.Request) {
+	var req entities.Availability
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid request body")
+		return
+	}
+
+	availability, err := h.service.UpdateAvailability(&req)
+	if err != nil {
+		if err.Error() == entities.ErrBadRequest {
+			writeError(w, http.StatusBadRequest, entities.ErrBadRequest, "start_date and end_date must be YYYY-MM-DD and ordered")
+			return
+		}
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "availability not found")
+			return
+		}
+		log.Printf("Error updating availability: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"availability": availability,
+	})
+}
+
+func (h *Handler) DeleteAvailability(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID int64 `json:"id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid request body")
+		return
+	}
+
+	if err := h.service.DeleteAvailability(req.ID); err != nil {
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "availability not found")
+			return
+		}
+		log.Printf("Error deleting availability: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id": req.ID,
+	})
+}
+
+const maxCalendarSize = 5 << 20
+
+// ImportAvailability accepts either a multipart form with a "file" part and an
+// optional JSON "mapping" field (attendee email -> user_id), or a raw
+// text/calendar body with the mapping passed as repeated map=email:user_id.
+func (h *Handler) ImportAvailability(w http.ResponseWriter, r *http.Request) {
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	mapping := make(map[string]string)
+
+	var data []byte
+	var err error
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(maxCalendarSize); err != nil {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid multipart body")
+			return
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", "file is required")
+			return
+		}
+		defer file.Close()
+		data, err = io.ReadAll(io.LimitReader(file, maxCalendarSize))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid request body")
+			return
+		}
+		if raw := r.FormValue("mapping"); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+				writeError(w, http.StatusBadRequest, "BAD_REQUEST", "mapping must be a JSON object")
+				return
+			}
+		}
+	} else {
+		data, err = io.ReadAll(io.LimitReader(r.Body, maxCalendarSize))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid request body")
+			return
+		}
+		for _, pair := range r.URL.Query()["map"] {
+			email, userID, ok := strings.Cut(pair, ":")
+			if !ok {
+				writeError(w, http.StatusBadRequest, "BAD_REQUEST", "map must be email:user_id")
+				return
+			}
+			mapping[email] = userID
+		}
+	}
+
+	result, err := h.service.ImportAvailability(data, mapping, dryRun)
+	if err != nil {
+		if err.Error() == entities.ErrBadRequest {
+			writeError(w, http.StatusBadRequest, entities.ErrBadRequest, "invalid iCalendar data")
+			return
+		}
+		log.Printf("Error importing availability: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
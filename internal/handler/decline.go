@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+func (h *Handler) DeclineReview(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		PullRequestID string `json:"pull_request_id"`
+		UserID        string `json:"user_id"`
+		Reason        string `json:"reason"`
+		Comment       string `json:"comment"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid request body")
+		return
+	}
+
+	pr, newReviewerID, err := h.service.DeclineReview(req.PullRequestID, req.UserID, req.Reason, req.Comment)
+	if err != nil {
+		if err.Error() == entities.ErrBadRequest {
+			writeError(w, http.StatusBadRequest, entities.ErrBadRequest,
+				"reason must be one of "+strings.Join(entities.DeclineReasons, ", "))
+			return
+		}
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "PR or user not found")
+			return
+		}
+		if err.Error() == entities.ErrPRMerged {
+			writeError(w, http.StatusConflict, entities.ErrPRMerged, "cannot decline on merged PR")
+			return
+		}
+		if err.Error() == entities.ErrPRClosed {
+			writeError(w, http.StatusConflict, entities.ErrPRClosed, "cannot decline on closed PR")
+			return
+		}
+		if err.Error() == entities.ErrNotAssigned {
+			writeError(w, http.StatusConflict, entities.ErrNotAssigned, "reviewer is not assigned to this PR")
+			return
+		}
+		log.Printf("Error declining review: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"pr":          pr,
+		"replaced_by": newReviewerID,
+	})
+}
+
+func (h *Handler) GetReviewerStats(w http.ResponseWriter, r *http.Request) {
+	teamName := r.URL.Query().Get("team_name")
+	userID := r.URL.Query().Get("user_id")
+
+	stats, err := h.service.GetReviewerStats(teamName, userID)
+	if err != nil {
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "team or user not found")
+			return
+		}
+		log.Printf("Error getting reviewer stats: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	if stats == nil {
+		stats = []entities.ReviewerStats{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"reviewers": stats,
+	})
+}
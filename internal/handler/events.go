@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+func (h *Handler) GetPRHistory(w http.ResponseWriter, r *http.Request) {
+	prID := r.URL.Query().Get("pull_request_id")
+	if prID == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "pull_request_id is required")
+		return
+	}
+
+	events, err := h.service.GetPRHistory(prID)
+	if err != nil {
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "PR not found")
+			return
+		}
+		log.Printf("Error getting PR history: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	if events == nil {
+		events = []entities.PREvent{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"pull_request_id": prID,
+		"events":          events,
+	})
+}
+
+func (h *Handler) ListEvents(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := entities.EventFilter{
+		Type:          q.Get("type"),
+		PullRequestID: q.Get("pull_request_id"),
+		UserID:        q.Get("user_id"),
+		TeamName:      q.Get("team_name"),
+		Actor:         q.Get("actor"),
+	}
+
+	var err error
+	if cursor := q.Get("cursor"); cursor != "" {
+		filter.AfterID, err = strconv.ParseInt(cursor, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid cursor")
+			return
+		}
+	}
+	if limit := q.Get("limit"); limit != "" {
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid limit")
+			return
+		}
+	}
+	if filter.Since, err = parseTimeParam(q.Get("since")); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "since must be RFC 3339")
+		return
+	}
+	if filter.Until, err = parseTimeParam(q.Get("until")); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "until must be RFC 3339")
+		return
+	}
+
+	events, nextCursor, err := h.service.ListEvents(filter)
+	if err != nil {
+		log.Printf("Error listing events: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	if events == nil {
+		events = []entities.PREvent{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"events":      events,
+		"next_cursor": nextCursor,
+	})
+}
+
+func parseTimeParam(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
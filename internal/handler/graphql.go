@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// GraphQL executes a GraphQL query or mutation. Service errors are reported
+// in the errors list of the response with extensions.code, so the status is
+// 200 unless the request itself is malformed.
+func (h *Handler) GraphQL(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Query         string                 `json:"query"`
+		OperationName string                 `json:"operationName"`
+		Variables     map[string]interface{} `json:"variables"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Query == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid request body")
+		return
+	}
+
+	resp := h.graphql.Exec(r.Context(), actorFrom(r), req.Query, req.OperationName, req.Variables)
+	writeJSON(w, http.StatusOK, resp)
+}
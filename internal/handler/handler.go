@@ -2,31 +2,99 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/alexalexbor04/pull_request_service/internal/entities"
+	"github.com/alexalexbor04/pull_request_service/internal/gql"
 	"github.com/alexalexbor04/pull_request_service/internal/service"
 )
 
 type Handler struct {
-	service *service.Service
+	service             *service.Service
+	graphql             *gql.Schema
+	githubWebhookSecret string
+	gitlabWebhookToken  string
 }
 
 func New(service *service.Service) *Handler {
-	return &Handler{service: service}
+	return &Handler{service: service, graphql: gql.NewSchema(service)}
 }
 
 func (h *Handler) SetupRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /team/add", h.AddTeam)
 	mux.HandleFunc("GET /team/get", h.GetTeam)
-
+	mux.HandleFunc("GET /team/capacity", h.GetTeamCapacity)
+	mux.HandleFunc("GET /team/sla", h.GetTeamSLA)
+	mux.HandleFunc("POST /team/setSla", h.SetTeamSLA)
+	mux.HandleFunc("GET /team/holidays", h.GetTeamHolidays)
+	mux.HandleFunc("POST /team/holidays/add", h.AddTeamHoliday)
+	mux.HandleFunc("POST /team/holidays/delete", h.DeleteTeamHoliday)
+	mux.HandleFunc("GET /team/stalePolicy", h.GetStalePolicy)
+	mux.HandleFunc("POST /team/setStalePolicy", h.SetStalePolicy)
+	mux.HandleFunc("GET /team/chat", h.GetChatSettings)
+	mux.HandleFunc("POST /team/setChat", h.SetChatSettings)
+	mux.HandleFunc("POST /team/sync", h.SyncTeams)
+
+	mux.HandleFunc("GET /users/get", h.GetUser)
+	mux.HandleFunc("GET /users/list", h.ListUsers)
+	mux.HandleFunc("POST /users/update", h.UpdateUser)
+	mux.HandleFunc("POST /users/delete", h.DeleteUser)
 	mux.HandleFunc("POST /users/setIsActive", h.SetUserActive)
 	mux.HandleFunc("GET /users/getReview", h.GetUserReviews)
-
+	mux.HandleFunc("POST /users/setMaxOpenReviews", h.SetUserMaxOpenReviews)
+	mux.HandleFunc("POST /users/setReviewWeight", h.SetUserReviewWeight)
+	mux.HandleFunc("GET /users/workSchedule", h.GetWorkSchedule)
+	mux.HandleFunc("POST /users/setWorkSchedule", h.SetWorkSchedule)
+	mux.HandleFunc("GET /users/logins", h.GetUserLogins)
+	mux.HandleFunc("POST /users/linkLogin", h.LinkLogin)
+	mux.HandleFunc("POST /users/unlinkLogin", h.UnlinkLogin)
+	mux.HandleFunc("POST /users/setChatMention", h.SetChatMention)
+	mux.HandleFunc("GET /users/emailPreferences", h.GetEmailPreferences)
+	mux.HandleFunc("POST /users/setEmailPreferences", h.SetEmailPreferences)
+
+	mux.HandleFunc("POST /availability/add", h.AddAvailability)
+	mux.HandleFunc("GET /availability/list", h.GetUserAvailability)
+	mux.HandleFunc("POST /availability/update", h.UpdateAvailability)
+	mux.HandleFunc("POST /availability/delete", h.DeleteAvailability)
+	mux.HandleFunc("POST /availability/import", h.ImportAvailability)
+
+	mux.HandleFunc("GET /pullRequest/get", h.GetPullRequest)
+	mux.HandleFunc("GET /pullRequest/list", h.ListPullRequests)
 	mux.HandleFunc("POST /pullRequest/create", h.CreatePullRequest)
 	mux.HandleFunc("POST /pullRequest/merge", h.MergePullRequest)
 	mux.HandleFunc("POST /pullRequest/reassign", h.ReassignReviewer)
+	mux.HandleFunc("GET /pullRequest/reviewerProbabilities", h.GetReviewerProbabilities)
+	mux.HandleFunc("POST /pullRequest/addReviewer", h.AddReviewer)
+	mux.HandleFunc("POST /pullRequest/removeReviewer", h.RemoveReviewer)
+	mux.HandleFunc("POST /pullRequest/decline", h.DeclineReview)
+	mux.HandleFunc("POST /pullRequest/review", h.SubmitReview)
+	mux.HandleFunc("GET /pullRequest/history", h.GetPRHistory)
+	mux.HandleFunc("GET /pullRequest/understaffed", h.GetUnderstaffedPRs)
+	mux.HandleFunc("POST /pullRequest/setLabels", h.SetPRLabels)
+	mux.HandleFunc("POST /pullRequest/reopen", h.ReopenPullRequest)
+	mux.HandleFunc("GET /pullRequest/stale", h.PreviewStalePRs)
+
+	mux.HandleFunc("GET /stats/reviewers", h.GetReviewerStats)
+	mux.HandleFunc("GET /reviews/overdue", h.GetOverdueReviews)
+
+	mux.HandleFunc("GET /audit", h.ListEvents)
+	mux.HandleFunc("GET /events/stream", h.StreamEvents)
+
+	mux.HandleFunc("POST /webhooks/subscriptions/add", h.CreateWebhookSubscription)
+	mux.HandleFunc("GET /webhooks/subscriptions/list", h.ListWebhookSubscriptions)
+	mux.HandleFunc("POST /webhooks/subscriptions/delete", h.DeleteWebhookSubscription)
+	mux.HandleFunc("GET /webhooks/deliveries", h.ListWebhookDeliveries)
+	mux.HandleFunc("POST /webhooks/deliveries/redeliver", h.RedeliverWebhook)
+	mux.HandleFunc("POST /webhooks/github", h.GitHubWebhook)
+	mux.HandleFunc("POST /webhooks/gitlab", h.GitLabWebhook)
+
+	mux.HandleFunc("GET /notifications", h.ListNotifications)
+	mux.HandleFunc("POST /notifications/retry", h.RetryNotification)
+
+	mux.HandleFunc("POST /graphql", h.GraphQL)
 }
 
 func writeJSON(w http.ResponseWriter, status int, data interface{}) {
@@ -46,6 +114,20 @@ func writeError(w http.ResponseWriter, status int, code, message string) {
 	})
 }
 
+// actorFrom identifies who performed a mutation for the audit log.
+func actorFrom(r *http.Request) string {
+	return r.Header.Get("X-Actor")
+}
+
+// errorDetail returns the detail of a DomainError or fallback for plain codes.
+func errorDetail(err error, fallback string) string {
+	var domainErr *entities.DomainError
+	if errors.As(err, &domainErr) && domainErr.Detail != "" {
+		return domainErr.Detail
+	}
+	return fallback
+}
+
 func (h *Handler) AddTeam(w http.ResponseWriter, r *http.Request) {
 	var team entities.Team
 	if err := json.NewDecoder(r.Body).Decode(&team); err != nil {
@@ -96,6 +178,34 @@ func (h *Handler) GetTeam(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, team)
 }
 
+func (h *Handler) GetTeamCapacity(w http.ResponseWriter, r *http.Request) {
+	teamName := r.URL.Query().Get("team_name")
+	if teamName == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "team_name is required")
+		return
+	}
+
+	members, err := h.service.GetTeamCapacity(teamName)
+	if err != nil {
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "team not found")
+			return
+		}
+		log.Printf("Error getting team capacity: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	if members == nil {
+		members = []entities.MemberCapacity{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"team_name": teamName,
+		"members":   members,
+	})
+}
+
 func (h *Handler) SetUserActive(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		UserID   string `json:"user_id"`
@@ -123,6 +233,68 @@ func (h *Handler) SetUserActive(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (h *Handler) SetUserMaxOpenReviews(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID         string `json:"user_id"`
+		MaxOpenReviews *int   `json:"max_open_reviews"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid request body")
+		return
+	}
+
+	user, err := h.service.SetUserMaxOpenReviews(req.UserID, req.MaxOpenReviews)
+	if err != nil {
+		if err.Error() == entities.ErrBadRequest {
+			writeError(w, http.StatusBadRequest, entities.ErrBadRequest, "max_open_reviews must be non-negative or null")
+			return
+		}
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "user not found")
+			return
+		}
+		log.Printf("Error setting max open reviews: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"user": user,
+	})
+}
+
+func (h *Handler) SetUserReviewWeight(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID       string  `json:"user_id"`
+		ReviewWeight float64 `json:"review_weight"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid request body")
+		return
+	}
+
+	user, err := h.service.SetUserReviewWeight(req.UserID, req.ReviewWeight)
+	if err != nil {
+		if err.Error() == entities.ErrBadRequest {
+			writeError(w, http.StatusBadRequest, entities.ErrBadRequest, "review_weight must be positive")
+			return
+		}
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "user not found")
+			return
+		}
+		log.Printf("Error setting review weight: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"user": user,
+	})
+}
+
 func (h *Handler) GetUserReviews(w http.ResponseWriter, r *http.Request) {
 	userID := r.URL.Query().Get("user_id")
 	if userID == "" {
@@ -130,8 +302,21 @@ func (h *Handler) GetUserReviews(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	prs, err := h.service.GetUserReviews(userID)
+	filter := entities.ReviewQueueFilter{Status: r.URL.Query().Get("status")}
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		var err error
+		if filter.Limit, err = strconv.Atoi(limit); err != nil {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid limit")
+			return
+		}
+	}
+
+	prs, nextCursor, err := h.service.GetUserReviews(userID, filter, r.URL.Query().Get("cursor"))
 	if err != nil {
+		if err.Error() == entities.ErrBadRequest {
+			writeError(w, http.StatusBadRequest, entities.ErrBadRequest, "status must be OPEN, MERGED, CLOSED or ALL; cursor must come from next_cursor")
+			return
+		}
 		if err.Error() == entities.ErrNotFound {
 			writeError(w, http.StatusNotFound, entities.ErrNotFound, "user not found")
 			return
@@ -148,6 +333,7 @@ func (h *Handler) GetUserReviews(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"user_id":       userID,
 		"pull_requests": prs,
+		"next_cursor":   nextCursor,
 	})
 }
 
@@ -199,6 +385,10 @@ func (h *Handler) MergePullRequest(w http.ResponseWriter, r *http.Request) {
 			writeError(w, http.StatusNotFound, entities.ErrNotFound, "PR not found")
 			return
 		}
+		if err.Error() == entities.ErrPRClosed {
+			writeError(w, http.StatusConflict, entities.ErrPRClosed, "cannot merge closed PR, reopen it first")
+			return
+		}
 		log.Printf("Error merging PR: %v", err)
 		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
 		return
@@ -213,6 +403,7 @@ func (h *Handler) ReassignReviewer(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		PullRequestID string `json:"pull_request_id"`
 		OldUserID     string `json:"old_user_id"`
+		Reason        string `json:"reason"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -220,7 +411,11 @@ func (h *Handler) ReassignReviewer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	pr, newReviewerID, err := h.service.ReassignReviewer(req.PullRequestID, req.OldUserID)
+	if req.Reason == "" {
+		req.Reason = "manual"
+	}
+
+	pr, newReviewerID, err := h.service.ReassignReviewer(req.PullRequestID, req.OldUserID, req.Reason, actorFrom(r))
 	if err != nil {
 		if err.Error() == entities.ErrNotFound {
 			writeError(w, http.StatusNotFound, entities.ErrNotFound, "PR or user not found")
@@ -230,12 +425,16 @@ func (h *Handler) ReassignReviewer(w http.ResponseWriter, r *http.Request) {
 			writeError(w, http.StatusConflict, entities.ErrPRMerged, "cannot reassign on merged PR")
 			return
 		}
+		if err.Error() == entities.ErrPRClosed {
+			writeError(w, http.StatusConflict, entities.ErrPRClosed, "cannot reassign on closed PR")
+			return
+		}
 		if err.Error() == entities.ErrNotAssigned {
 			writeError(w, http.StatusConflict, entities.ErrNotAssigned, "reviewer is not assigned to this PR")
 			return
 		}
 		if err.Error() == entities.ErrNoCandidate {
-			writeError(w, http.StatusConflict, entities.ErrNoCandidate, "no active replacement candidate in team")
+			writeError(w, http.StatusConflict, entities.ErrNoCandidate, errorDetail(err, "no active replacement candidate in team"))
 			return
 		}
 		log.Printf("Error reassigning reviewer: %v", err)
@@ -249,5 +448,26 @@ func (h *Handler) ReassignReviewer(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (h *Handler) GetReviewerProbabilities(w http.ResponseWriter, r *http.Request) {
+	authorID := r.URL.Query().Get("author_id")
+	if authorID == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "author_id is required")
+		return
+	}
 
+	candidates, err := h.service.GetReviewerProbabilities(authorID)
+	if err != nil {
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "author not found")
+			return
+		}
+		log.Printf("Error getting reviewer probabilities: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
 
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"author_id":  authorID,
+		"candidates": candidates,
+	})
+}
@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+func (h *Handler) GetChatSettings(w http.ResponseWriter, r *http.Request) {
+	teamName := r.URL.Query().Get("team_name")
+	if teamName == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "team_name is required")
+		return
+	}
+
+	settings, err := h.service.GetChatSettings(teamName)
+	if err != nil {
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "team not found")
+			return
+		}
+		log.Printf("Error getting chat settings: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"chat": settings,
+	})
+}
+
+func (h *Handler) SetChatSettings(w http.ResponseWriter, r *http.Request) {
+	var req entities.ChatSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid request body")
+		return
+	}
+
+	settings, err := h.service.SetChatSettings(&req, actorFrom(r))
+	if err != nil {
+		if err.Error() == entities.ErrBadRequest {
+			writeError(w, http.StatusBadRequest, entities.ErrBadRequest, errorDetail(err, "invalid chat settings"))
+			return
+		}
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "team not found")
+			return
+		}
+		log.Printf("Error setting chat settings: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"chat": settings,
+	})
+}
+
+func (h *Handler) SetChatMention(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID      string `json:"user_id"`
+		ChatMention string `json:"chat_mention"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid request body")
+		return
+	}
+
+	if err := h.service.SetChatMention(req.UserID, req.ChatMention, actorFrom(r)); err != nil {
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "user not found")
+			return
+		}
+		log.Printf("Error setting chat mention: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"user_id":      req.UserID,
+		"chat_mention": req.ChatMention,
+	})
+}
+
+func (h *Handler) GetEmailPreferences(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "user_id is required")
+		return
+	}
+
+	prefs, err := h.service.GetEmailPreferences(userID)
+	if err != nil {
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "user not found")
+			return
+		}
+		log.Printf("Error getting email preferences: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"preferences": prefs,
+	})
+}
+
+func (h *Handler) SetEmailPreferences(w http.ResponseWriter, r *http.Request) {
+	var req entities.EmailPreferences
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid request body")
+		return
+	}
+
+	prefs, err := h.service.SetEmailPreferences(&req, actorFrom(r))
+	if err != nil {
+		if err.Error() == entities.ErrBadRequest {
+			writeError(w, http.StatusBadRequest, entities.ErrBadRequest, errorDetail(err, "invalid email preferences"))
+			return
+		}
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "user not found")
+			return
+		}
+		log.Printf("Error setting email preferences: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"preferences": prefs,
+	})
+}
+
+func (h *Handler) ListNotifications(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := entities.NotificationFilter{Channel: q.Get("channel"), Status: q.Get("status")}
+
+	var err error
+	if cursor := q.Get("cursor"); cursor != "" {
+		filter.AfterID, err = strconv.ParseInt(cursor, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid cursor")
+			return
+		}
+	}
+	if limit := q.Get("limit"); limit != "" {
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid limit")
+			return
+		}
+	}
+
+	notifications, nextCursor, err := h.service.ListNotifications(filter)
+	if err != nil {
+		if err.Error() == entities.ErrBadRequest {
+			writeError(w, http.StatusBadRequest, entities.ErrBadRequest, "status must be PENDING, SENT or DEAD")
+			return
+		}
+		log.Printf("Error listing notifications: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	if notifications == nil {
+		notifications = []entities.Notification{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"notifications": notifications,
+		"next_cursor":   nextCursor,
+	})
+}
+
+func (h *Handler) RetryNotification(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID int64 `json:"id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid request body")
+		return
+	}
+
+	notification, err := h.service.RetryNotification(req.ID)
+	if err != nil {
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "notification not found")
+			return
+		}
+		log.Printf("Error retrying notification: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"notification": notification,
+	})
+}
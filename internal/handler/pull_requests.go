@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+func (h *Handler) GetPullRequest(w http.ResponseWriter, r *http.Request) {
+	prID := r.URL.Query().Get("pull_request_id")
+	if prID == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "pull_request_id is required")
+		return
+	}
+
+	pr, err := h.service.GetPullRequest(prID)
+	if err != nil {
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "PR not found")
+			return
+		}
+		log.Printf("Error getting PR: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"pr": pr,
+	})
+}
+
+func (h *Handler) ListPullRequests(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := entities.PRFilter{
+		Status:       q.Get("status"),
+		AuthorID:     q.Get("author_id"),
+		ReviewerID:   q.Get("reviewer_id"),
+		TeamName:     q.Get("team_name"),
+		NameContains: q.Get("name"),
+	}
+
+	switch q.Get("sort") {
+	case "", "created_at_desc":
+	case "created_at_asc":
+		filter.Ascending = true
+	default:
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "sort must be created_at_asc or created_at_desc")
+		return
+	}
+
+	var err error
+	if limit := q.Get("limit"); limit != "" {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid limit")
+			return
+		}
+	}
+
+	timeParams := []struct {
+		name string
+		dst  **time.Time
+	}{
+		{"created_from", &filter.CreatedFrom},
+		{"created_to", &filter.CreatedTo},
+		{"merged_from", &filter.MergedFrom},
+		{"merged_to", &filter.MergedTo},
+	}
+	for _, p := range timeParams {
+		if *p.dst, err = parseTimeParam(q.Get(p.name)); err != nil {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", p.name+" must be RFC 3339")
+			return
+		}
+	}
+
+	prs, nextCursor, err := h.service.ListPullRequests(filter, q.Get("cursor"))
+	if err != nil {
+		if err.Error() == entities.ErrBadRequest {
+			writeError(w, http.StatusBadRequest, entities.ErrBadRequest, "invalid status or cursor")
+			return
+		}
+		log.Printf("Error listing PRs: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	if prs == nil {
+		prs = []entities.PullRequest{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"pull_requests": prs,
+		"next_cursor":   nextCursor,
+	})
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+type reviewerRequest struct {
+	PullRequestID string `json:"pull_request_id"`
+	UserID        string `json:"user_id"`
+}
+
+func (h *Handler) AddReviewer(w http.ResponseWriter, r *http.Request) {
+	var req reviewerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid request body")
+		return
+	}
+
+	pr, err := h.service.AddReviewer(req.PullRequestID, req.UserID, actorFrom(r))
+	if err != nil {
+		writeReviewerError(w, err, "Error adding reviewer")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"pr": pr,
+	})
+}
+
+func (h *Handler) RemoveReviewer(w http.ResponseWriter, r *http.Request) {
+	var req reviewerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid request body")
+		return
+	}
+
+	pr, err := h.service.RemoveReviewer(req.PullRequestID, req.UserID, actorFrom(r))
+	if err != nil {
+		writeReviewerError(w, err, "Error removing reviewer")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"pr": pr,
+	})
+}
+
+// writeReviewerError maps reviewer mutation errors to the same codes and
+// statuses as POST /pullRequest/reassign.
+func writeReviewerError(w http.ResponseWriter, err error, logPrefix string) {
+	switch err.Error() {
+	case entities.ErrNotFound:
+		writeError(w, http.StatusNotFound, entities.ErrNotFound, "PR or user not found")
+	case entities.ErrPRMerged:
+		writeError(w, http.StatusConflict, entities.ErrPRMerged, "cannot change reviewers on merged PR")
+	case entities.ErrPRClosed:
+		writeError(w, http.StatusConflict, entities.ErrPRClosed, "cannot change reviewers on closed PR")
+	case entities.ErrNotAssigned:
+		writeError(w, http.StatusConflict, entities.ErrNotAssigned, "reviewer is not assigned to this PR")
+	case entities.ErrNoCandidate:
+		writeError(w, http.StatusConflict, entities.ErrNoCandidate, errorDetail(err, "user cannot be assigned as reviewer"))
+	default:
+		log.Printf("%s: %v", logPrefix, err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+	}
+}
+
+func (h *Handler) GetUnderstaffedPRs(w http.ResponseWriter, r *http.Request) {
+	teamName := r.URL.Query().Get("team_name")
+
+	prs, err := h.service.GetUnderstaffedPRs(teamName)
+	if err != nil {
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "team not found")
+			return
+		}
+		log.Printf("Error getting under-staffed PRs: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	if prs == nil {
+		prs = []entities.UnderstaffedPR{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"pull_requests": prs,
+	})
+}
+
+func (h *Handler) SubmitReview(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		PullRequestID string `json:"pull_request_id"`
+		UserID        string `json:"user_id"`
+		Verdict       string `json:"verdict"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid request body")
+		return
+	}
+
+	pr, err := h.service.SubmitReview(req.PullRequestID, req.UserID, req.Verdict)
+	if err != nil {
+		if err.Error() == entities.ErrBadRequest {
+			writeError(w, http.StatusBadRequest, entities.ErrBadRequest, "verdict must be APPROVED or CHANGES_REQUESTED")
+			return
+		}
+		writeReviewerError(w, err, "Error submitting review")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"pr": pr,
+	})
+}
@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+	"github.com/alexalexbor04/pull_request_service/internal/scm"
+)
+
+const maxWebhookBody = 5 << 20
+
+// SetGitHubWebhookSecret enables POST /webhooks/github. Deliveries must be
+// signed with secret.
+func (h *Handler) SetGitHubWebhookSecret(secret string) {
+	h.githubWebhookSecret = secret
+}
+
+func (h *Handler) GitHubWebhook(w http.ResponseWriter, r *http.Request) {
+	if h.githubWebhookSecret == "" {
+		writeError(w, http.StatusNotFound, entities.ErrNotFound, "GitHub webhooks are not configured")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid request body")
+		return
+	}
+	if !scm.VerifyGitHubSignature(h.githubWebhookSecret, body, r.Header.Get("X-Hub-Signature-256")) {
+		writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid signature")
+		return
+	}
+
+	event := r.Header.Get("X-GitHub-Event")
+	if event == "ping" {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"result": "pong",
+		})
+		return
+	}
+
+	change, err := scm.ParseGitHub(event, body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", err.Error())
+		return
+	}
+
+	h.handleSCMChange(w, scm.ProviderGitHub, r.Header.Get("X-GitHub-Delivery"), change)
+}
+
+// SetGitLabWebhookToken enables POST /webhooks/gitlab. Deliveries must carry
+// token in X-Gitlab-Token.
+func (h *Handler) SetGitLabWebhookToken(token string) {
+	h.gitlabWebhookToken = token
+}
+
+func (h *Handler) GitLabWebhook(w http.ResponseWriter, r *http.Request) {
+	if h.gitlabWebhookToken == "" {
+		writeError(w, http.StatusNotFound, entities.ErrNotFound, "GitLab webhooks are not configured")
+		return
+	}
+	if !scm.VerifyGitLabToken(h.gitlabWebhookToken, r.Header.Get("X-Gitlab-Token")) {
+		writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid token")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid request body")
+		return
+	}
+
+	change, err := scm.ParseGitLab(r.Header.Get("X-Gitlab-Event"), body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", err.Error())
+		return
+	}
+
+	h.handleSCMChange(w, scm.ProviderGitLab, r.Header.Get("X-Gitlab-Event-UUID"), change)
+}
+
+func (h *Handler) handleSCMChange(w http.ResponseWriter, provider, deliveryID string, change *scm.Change) {
+	result, err := h.service.HandleSCMChange(provider, deliveryID, change)
+	if err != nil {
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, errorDetail(err, "user not found"))
+			return
+		}
+		log.Printf("Error handling %s webhook: %v", provider, err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"result": result,
+	})
+}
+
+func (h *Handler) LinkLogin(w http.ResponseWriter, r *http.Request) {
+	var req entities.UserLogin
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid request body")
+		return
+	}
+
+	if err := h.service.LinkLogin(&req, actorFrom(r)); err != nil {
+		if err.Error() == entities.ErrBadRequest {
+			writeError(w, http.StatusBadRequest, entities.ErrBadRequest, "provider must be github or gitlab and login is required")
+			return
+		}
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "user not found")
+			return
+		}
+		log.Printf("Error linking login: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"login": req,
+	})
+}
+
+func (h *Handler) UnlinkLogin(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Provider string `json:"provider"`
+		Login    string `json:"login"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid request body")
+		return
+	}
+
+	if err := h.service.UnlinkLogin(req.Provider, req.Login, actorFrom(r)); err != nil {
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "login not linked")
+			return
+		}
+		log.Printf("Error unlinking login: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"provider": req.Provider,
+		"login":    req.Login,
+	})
+}
+
+func (h *Handler) GetUserLogins(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "user_id is required")
+		return
+	}
+
+	logins, err := h.service.GetUserLogins(userID)
+	if err != nil {
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "user not found")
+			return
+		}
+		log.Printf("Error getting user logins: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	if logins == nil {
+		logins = []entities.UserLogin{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"user_id": userID,
+		"logins":  logins,
+	})
+}
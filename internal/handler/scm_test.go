@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+	"github.com/alexalexbor04/pull_request_service/internal/repos"
+	"github.com/alexalexbor04/pull_request_service/internal/service"
+	"github.com/alexalexbor04/pull_request_service/internal/testdb"
+)
+
+const testWebhookSecret = "s3cret"
+
+// githubFixture loads a recorded pull_request payload and points it at a
+// repository and author of the test's own.
+func githubFixture(t *testing.T, name, repo, author string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile("../scm/testdata/github/" + name)
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		t.Fatalf("parse fixture: %v", err)
+	}
+	payload["repository"].(map[string]interface{})["full_name"] = repo
+	payload["pull_request"].(map[string]interface{})["user"].(map[string]interface{})["login"] = author
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("encode fixture: %v", err)
+	}
+	return body
+}
+
+func sendGitHubWebhook(t *testing.T, h *Handler, deliveryID string, body []byte) (int, string) {
+	t.Helper()
+
+	mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+	mac.Write(body)
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/github", bytes.NewReader(body))
+	req.Header.Set("X-GitHub-Event", "pull_request")
+	req.Header.Set("X-GitHub-Delivery", deliveryID)
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	rec := httptest.NewRecorder()
+	h.GitHubWebhook(rec, req)
+
+	var resp struct {
+		Result string `json:"result"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	return rec.Code, resp.Result
+}
+
+// newSCMTest sets up a handler with a team whose author matches the
+// rewritten fixtures and returns it with a suffix unique to the test.
+func newSCMTest(t *testing.T) (*Handler, string) {
+	t.Helper()
+
+	svc := service.New(repos.New(testdb.Open(t)))
+	h := New(svc)
+	h.SetGitHubWebhookSecret(testWebhookSecret)
+
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+	err := svc.CreateTeam(&entities.Team{
+		TeamName: "scm-" + suffix,
+		Members: []entities.TeamMember{
+			{UserID: "octocat-" + suffix, Username: "Octocat", IsActive: true},
+			{UserID: "hubot-" + suffix, Username: "Hubot", IsActive: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateTeam: %v", err)
+	}
+	return h, suffix
+}
+
+func TestGitHubWebhookDuplicateDelivery(t *testing.T) {
+	h, suffix := newSCMTest(t)
+	body := githubFixture(t, "pull_request_opened.json", "acme/backend-"+suffix, "octocat-"+suffix)
+	deliveryID := "delivery-" + suffix
+
+	if code, result := sendGitHubWebhook(t, h, deliveryID, body); code != http.StatusOK || result != service.SCMResultCreated {
+		t.Fatalf("first delivery = %d %q, want 200 %q", code, result, service.SCMResultCreated)
+	}
+	if code, result := sendGitHubWebhook(t, h, deliveryID, body); code != http.StatusOK || result != service.SCMResultDuplicate {
+		t.Fatalf("second delivery = %d %q, want 200 %q", code, result, service.SCMResultDuplicate)
+	}
+}
+
+func TestGitHubWebhookConcurrentDuplicates(t *testing.T) {
+	h, suffix := newSCMTest(t)
+	body := githubFixture(t, "pull_request_opened.json", "acme/backend-"+suffix, "octocat-"+suffix)
+	deliveryID := "delivery-" + suffix
+
+	const copies = 4
+	results := make(chan string, copies)
+	var wg sync.WaitGroup
+	for i := 0; i < copies; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			code, result := sendGitHubWebhook(t, h, deliveryID, body)
+			if code != http.StatusOK {
+				result = strconv.Itoa(code)
+			}
+			results <- result
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	counts := make(map[string]int)
+	for result := range results {
+		counts[result]++
+	}
+	if counts[service.SCMResultCreated] != 1 || counts[service.SCMResultDuplicate]+counts[service.SCMResultIgnored] != copies-1 {
+		t.Fatalf("results of %d concurrent copies = %v, want one %q and the rest %q or %q",
+			copies, counts, service.SCMResultCreated, service.SCMResultDuplicate, service.SCMResultIgnored)
+	}
+}
+
+func TestGitHubWebhookRejectsBadSignature(t *testing.T) {
+	h := &Handler{}
+	h.SetGitHubWebhookSecret(testWebhookSecret)
+
+	body := githubFixture(t, "pull_request_opened.json", "acme/backend", "octocat")
+	for name, signature := range map[string]string{"wrong": "sha256=00", "missing": ""} {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/github", bytes.NewReader(body))
+		req.Header.Set("X-GitHub-Event", "pull_request")
+		if signature != "" {
+			req.Header.Set("X-Hub-Signature-256", signature)
+		}
+		rec := httptest.NewRecorder()
+		h.GitHubWebhook(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s signature: status %d, want 401", name, rec.Code)
+		}
+	}
+}
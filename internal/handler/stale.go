@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+func (h *Handler) GetStalePolicy(w http.ResponseWriter, r *http.Request) {
+	teamName := r.URL.Query().Get("team_name")
+	if teamName == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "team_name is required")
+		return
+	}
+
+	policy, err := h.service.GetStalePolicy(teamName)
+	if err != nil {
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "team not found")
+			return
+		}
+		log.Printf("Error getting stale policy: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"policy": policy,
+	})
+}
+
+func (h *Handler) SetStalePolicy(w http.ResponseWriter, r *http.Request) {
+	var req entities.StalePolicy
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid request body")
+		return
+	}
+
+	policy, err := h.service.SetStalePolicy(&req, actorFrom(r))
+	if err != nil {
+		if err.Error() == entities.ErrBadRequest {
+			writeError(w, http.StatusBadRequest, entities.ErrBadRequest, "days must be positive and close_after_days greater than warn_after_days")
+			return
+		}
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "team not found")
+			return
+		}
+		log.Printf("Error setting stale policy: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"policy": policy,
+	})
+}
+
+func (h *Handler) PreviewStalePRs(w http.ResponseWriter, r *http.Request) {
+	teamName := r.URL.Query().Get("team_name")
+
+	prs, err := h.service.PreviewStalePRs(teamName)
+	if err != nil {
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "team not found")
+			return
+		}
+		log.Printf("Error previewing stale PRs: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"pull_requests": prs,
+	})
+}
+
+func (h *Handler) SetPRLabels(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		PullRequestID string   `json:"pull_request_id"`
+		Labels        []string `json:"labels"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid request body")
+		return
+	}
+
+	pr, err := h.service.SetPRLabels(req.PullRequestID, req.Labels, actorFrom(r))
+	if err != nil {
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "PR not found")
+			return
+		}
+		log.Printf("Error setting PR labels: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"pr": pr,
+	})
+}
+
+func (h *Handler) ReopenPullRequest(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		PullRequestID string `json:"pull_request_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid request body")
+		return
+	}
+
+	pr, err := h.service.ReopenPullRequest(req.PullRequestID, actorFrom(r))
+	if err != nil {
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "PR not found")
+			return
+		}
+		if err.Error() == entities.ErrPRMerged {
+			writeError(w, http.StatusConflict, entities.ErrPRMerged, "cannot reopen merged PR")
+			return
+		}
+		log.Printf("Error reopening PR: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"pr": pr,
+	})
+}
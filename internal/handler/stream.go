@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+const (
+	streamHeartbeatInterval = 15 * time.Second
+	// streamWriteTimeout replaces the server's WriteTimeout, which would
+	// otherwise cut every stream off shortly after it started, with a
+	// deadline per write.
+	streamWriteTimeout = 10 * time.Second
+	streamRetry        = 3 * time.Second
+)
+
+// StreamEvents pushes PR events as Server-Sent Events. A client resuming with
+// Last-Event-ID (or ?last_event_id= where headers cannot be set) first gets
+// the matching events it missed from the event log.
+func (h *Handler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := entities.EventFilter{
+		Types:    entities.StreamEventTypes,
+		TeamName: q.Get("team_name"),
+		UserID:   q.Get("user_id"),
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = q.Get("last_event_id")
+	}
+	resume := lastEventID != ""
+	if resume {
+		var err error
+		filter.AfterID, err = strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid Last-Event-ID")
+			return
+		}
+	}
+
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout)); err != nil {
+		log.Printf("Error streaming events: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Streaming is not supported")
+		return
+	}
+
+	sub, err := h.service.SubscribeEvents(filter)
+	if err != nil {
+		if err.Error() == entities.ErrUnavailable {
+			writeError(w, http.StatusServiceUnavailable, entities.ErrUnavailable, errorDetail(err, "event stream is not running"))
+			return
+		}
+		log.Printf("Error subscribing to events: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	write := func(format string, args ...interface{}) error {
+		if err := rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout)); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+	send := func(e entities.PREvent) error {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		return write("id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	}
+
+	if err := write("retry: %d\n\n", streamRetry.Milliseconds()); err != nil {
+		return
+	}
+
+	// Events above sub.Since may also arrive live; replayed ones are skipped.
+	replayed := make(map[int64]bool)
+	for resume {
+		events, nextCursor, err := h.service.ListEvents(filter)
+		if err != nil {
+			log.Printf("Error replaying events: %v", err)
+			return
+		}
+		for _, e := range events {
+			if err := send(e); err != nil {
+				return
+			}
+			if e.ID > sub.Since {
+				replayed[e.ID] = true
+			}
+		}
+		if nextCursor == "" {
+			break
+		}
+		filter.AfterID = events[len(events)-1].ID
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if err := write(": heartbeat\n\n"); err != nil {
+				return
+			}
+		case e, ok := <-sub.Events:
+			if !ok {
+				return
+			}
+			if replayed[e.ID] {
+				continue
+			}
+			if err := send(e); err != nil {
+				return
+			}
+		}
+	}
+}
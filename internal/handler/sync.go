@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+// SyncTeams brings teams and users to the desired state in the body. With
+// dry_run=true the plan is returned without applying it.
+func (h *Handler) SyncTeams(w http.ResponseWriter, r *http.Request) {
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+
+	var req entities.OrgState
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid request body")
+		return
+	}
+
+	result, err := h.service.SyncOrg(&req, dryRun, actorFrom(r))
+	if err != nil {
+		if err.Error() == entities.ErrBadRequest {
+			writeError(w, http.StatusBadRequest, entities.ErrBadRequest, errorDetail(err, "invalid team state"))
+			return
+		}
+		log.Printf("Error syncing teams: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "user_id is required")
+		return
+	}
+
+	user, err := h.service.GetUser(userID)
+	if err != nil {
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "user not found")
+			return
+		}
+		log.Printf("Error getting user: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"user": user,
+	})
+}
+
+func (h *Handler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := entities.UserFilter{
+		TeamName: q.Get("team_name"),
+		AfterID:  q.Get("cursor"),
+	}
+
+	if active := q.Get("is_active"); active != "" {
+		isActive, err := strconv.ParseBool(active)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", "is_active must be true or false")
+			return
+		}
+		filter.IsActive = &isActive
+	}
+	if limit := q.Get("limit"); limit != "" {
+		var err error
+		if filter.Limit, err = strconv.Atoi(limit); err != nil {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid limit")
+			return
+		}
+	}
+
+	users, nextCursor, err := h.service.ListUsers(filter)
+	if err != nil {
+		log.Printf("Error listing users: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	if users == nil {
+		users = []entities.User{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"users":       users,
+		"next_cursor": nextCursor,
+	})
+}
+
+func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID string `json:"user_id"`
+		entities.UserUpdate
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid request body")
+		return
+	}
+
+	user, err := h.service.UpdateUser(req.UserID, req.UserUpdate, actorFrom(r))
+	if err != nil {
+		if err.Error() == entities.ErrBadRequest {
+			writeError(w, http.StatusBadRequest, entities.ErrBadRequest, errorDetail(err, "username must not be empty"))
+			return
+		}
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "user not found")
+			return
+		}
+		log.Printf("Error updating user: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"user": user,
+	})
+}
+
+func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID    string `json:"user_id"`
+		Anonymize bool   `json:"anonymize"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid request body")
+		return
+	}
+
+	if err := h.service.DeleteUser(req.UserID, req.Anonymize, actorFrom(r)); err != nil {
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "user not found")
+			return
+		}
+		if err.Error() == entities.ErrUserReferenced {
+			writeError(w, http.StatusConflict, entities.ErrUserReferenced, errorDetail(err, "user is referenced by pull requests"))
+			return
+		}
+		log.Printf("Error deleting user: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"user_id":    req.UserID,
+		"anonymized": req.Anonymize,
+	})
+}
@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+func (h *Handler) CreateWebhookSubscription(w http.ResponseWriter, r *http.Request) {
+	var req entities.WebhookSubscription
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid request body")
+		return
+	}
+
+	sub, err := h.service.CreateWebhookSubscription(&req)
+	if err != nil {
+		if err.Error() == entities.ErrBadRequest {
+			writeError(w, http.StatusBadRequest, entities.ErrBadRequest, errorDetail(err, "invalid subscription"))
+			return
+		}
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "team not found")
+			return
+		}
+		if err.Error() == entities.ErrUnavailable {
+			writeError(w, http.StatusServiceUnavailable, entities.ErrUnavailable, errorDetail(err, "webhook subscriptions are disabled"))
+			return
+		}
+		log.Printf("Error creating webhook subscription: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"subscription": sub,
+	})
+}
+
+func (h *Handler) ListWebhookSubscriptions(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.service.ListWebhookSubscriptions()
+	if err != nil {
+		log.Printf("Error listing webhook subscriptions: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	if subs == nil {
+		subs = []entities.WebhookSubscription{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"subscriptions": subs,
+	})
+}
+
+func (h *Handler) DeleteWebhookSubscription(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID int64 `json:"id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid request body")
+		return
+	}
+
+	if err := h.service.DeleteWebhookSubscription(req.ID); err != nil {
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "subscription not found")
+			return
+		}
+		log.Printf("Error deleting webhook subscription: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id": req.ID,
+	})
+}
+
+func (h *Handler) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := entities.WebhookDeliveryFilter{Status: q.Get("status")}
+
+	var err error
+	if id := q.Get("subscription_id"); id != "" {
+		filter.SubscriptionID, err = strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid subscription_id")
+			return
+		}
+	}
+	if cursor := q.Get("cursor"); cursor != "" {
+		filter.AfterID, err = strconv.ParseInt(cursor, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid cursor")
+			return
+		}
+	}
+	if limit := q.Get("limit"); limit != "" {
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid limit")
+			return
+		}
+	}
+
+	deliveries, nextCursor, err := h.service.ListWebhookDeliveries(filter)
+	if err != nil {
+		if err.Error() == entities.ErrBadRequest {
+			writeError(w, http.StatusBadRequest, entities.ErrBadRequest, "status must be PENDING, DELIVERED or DEAD")
+			return
+		}
+		log.Printf("Error listing webhook deliveries: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	if deliveries == nil {
+		deliveries = []entities.WebhookDelivery{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"deliveries":  deliveries,
+		"next_cursor": nextCursor,
+	})
+}
+
+func (h *Handler) RedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID int64 `json:"id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid request body")
+		return
+	}
+
+	delivery, err := h.service.RedeliverWebhook(req.ID)
+	if err != nil {
+		if err.Error() == entities.ErrNotFound {
+			writeError(w, http.StatusNotFound, entities.ErrNotFound, "delivery not found")
+			return
+		}
+		if err.Error() == entities.ErrDeliveryNotFailed {
+			writeError(w, http.StatusConflict, entities.ErrDeliveryNotFailed, errorDetail(err, "only failed deliveries can be redelivered"))
+			return
+		}
+		log.Printf("Error redelivering webhook: %v", err)
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"delivery": delivery,
+	})
+}
@@ -0,0 +1,184 @@
+package ical
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Event is the subset of a VEVENT needed to build availability windows.
+// End is inclusive: an all-day event with DTEND 2025-01-10 ends on 2025-01-09.
+type Event struct {
+	UID       string
+	Summary   string
+	Status    string
+	Start     time.Time
+	End       time.Time
+	Attendees []string
+	UserID    string
+}
+
+var ErrMalformed = errors.New("malformed iCalendar data")
+
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Parse extracts VEVENT components from an iCalendar document.
+func Parse(data []byte) ([]Event, error) {
+	lines, err := unfold(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	var current *Event
+	var endIsDate bool
+	var hasEnd bool
+
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		prop, ok := parseProperty(line)
+		if !ok {
+			return nil, ErrMalformed
+		}
+
+		switch {
+		case prop.name == "BEGIN" && strings.EqualFold(prop.value, "VEVENT"):
+			current = &Event{}
+			endIsDate, hasEnd = false, false
+		case prop.name == "END" && strings.EqualFold(prop.value, "VEVENT"):
+			if current == nil || current.Start.IsZero() {
+				return nil, ErrMalformed
+			}
+			switch {
+			case !hasEnd:
+				current.End = current.Start
+			case endIsDate || isMidnight(current.End):
+				current.End = current.End.AddDate(0, 0, -1)
+			}
+			if current.End.Before(current.Start) {
+				current.End = current.Start
+			}
+			events = append(events, *current)
+			current = nil
+		case current == nil:
+			continue
+		case prop.name == "UID":
+			current.UID = prop.value
+		case prop.name == "SUMMARY":
+			current.Summary = unescape(prop.value)
+		case prop.name == "STATUS":
+			current.Status = strings.ToUpper(prop.value)
+		case prop.name == "X-USER-ID":
+			current.UserID = prop.value
+		case prop.name == "ATTENDEE":
+			email := strings.TrimPrefix(strings.TrimPrefix(prop.value, "mailto:"), "MAILTO:")
+			if email != "" {
+				current.Attendees = append(current.Attendees, strings.ToLower(email))
+			}
+		case prop.name == "DTSTART":
+			t, _, err := parseTime(prop)
+			if err != nil {
+				return nil, err
+			}
+			current.Start = t
+		case prop.name == "DTEND":
+			t, isDate, err := parseTime(prop)
+			if err != nil {
+				return nil, err
+			}
+			current.End = t
+			endIsDate, hasEnd = isDate, true
+		}
+	}
+
+	if current != nil {
+		return nil, ErrMalformed
+	}
+
+	return events, nil
+}
+
+func unfold(data []byte) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+func parseProperty(line string) (property, bool) {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return property{}, false
+	}
+
+	head := strings.Split(line[:colon], ";")
+	prop := property{
+		name:   strings.ToUpper(head[0]),
+		params: make(map[string]string),
+		value:  line[colon+1:],
+	}
+	for _, param := range head[1:] {
+		key, value, _ := strings.Cut(param, "=")
+		prop.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+	}
+
+	return prop, true
+}
+
+// parseTime returns the calendar date of a DATE or DATE-TIME value and whether
+// the value was a plain DATE.
+func parseTime(prop property) (time.Time, bool, error) {
+	value := prop.value
+	if prop.params["VALUE"] == "DATE" || len(value) == 8 {
+		t, err := time.Parse("20060102", value)
+		if err != nil {
+			return time.Time{}, false, ErrMalformed
+		}
+		return t, true, nil
+	}
+
+	loc := time.UTC
+	if tzid := prop.params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+
+	var t time.Time
+	var err error
+	if strings.HasSuffix(value, "Z") {
+		t, err = time.Parse("20060102T150405Z", value)
+	} else {
+		t, err = time.ParseInLocation("20060102T150405", value, loc)
+	}
+	if err != nil {
+		return time.Time{}, false, ErrMalformed
+	}
+
+	return t, false, nil
+}
+
+func isMidnight(t time.Time) bool {
+	return t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0
+}
+
+func unescape(s string) string {
+	r := strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`)
+	return r.Replace(s)
+}
@@ -0,0 +1,141 @@
+package mail
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexalexbor04/pull_request_service/internal/mail/mailtest"
+)
+
+func newTestMailer(t *testing.T) (*Mailer, *mailtest.Server) {
+	t.Helper()
+
+	srv := mailtest.NewServer()
+	t.Cleanup(srv.Close)
+	return &Mailer{Addr: srv.Addr, From: "Review Bot <bot@example.com>"}, srv
+}
+
+func TestSend(t *testing.T) {
+	m, srv := newTestMailer(t)
+
+	msg := Message{
+		To:      "jane@example.com",
+		ToName:  "Jane Doe",
+		Subject: "Review requested: Café menu",
+		Text:    "Hi Jane,\n\nalice asked you to review \"Café menu\" (pr-1001), a change with a title = long enough to wrap.\n",
+		HTML:    "<p>Hi Jane,</p>\n<p>alice asked you to review <b>Café menu</b> (pr-1001), a change with a title = long enough to wrap.</p>",
+	}
+	if err := m.Send(msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	messages := srv.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("server received %d messages, want 1", len(messages))
+	}
+	got := messages[0]
+	if got.From != "bot@example.com" || len(got.To) != 1 || got.To[0] != "jane@example.com" {
+		t.Fatalf("envelope = %s -> %v, want bot@example.com -> [jane@example.com]", got.From, got.To)
+	}
+
+	parsed, err := got.Parse()
+	if err != nil {
+		t.Fatalf("parse message: %v", err)
+	}
+	if from := parsed.Header.Get("From"); from != `"Review Bot" <bot@example.com>` {
+		t.Errorf("From = %q", from)
+	}
+	if to := parsed.Header.Get("To"); to != `"Jane Doe" <jane@example.com>` {
+		t.Errorf("To = %q", to)
+	}
+	if id := parsed.Header.Get("Message-ID"); !strings.HasSuffix(id, "@example.com>") {
+		t.Errorf("Message-ID = %q, want one on the sender's domain", id)
+	}
+	if parsed.Subject != msg.Subject {
+		t.Errorf("Subject = %q, want %q", parsed.Subject, msg.Subject)
+	}
+	if parsed.Text != msg.Text {
+		t.Errorf("text part = %q, want %q", parsed.Text, msg.Text)
+	}
+	if parsed.HTML != msg.HTML {
+		t.Errorf("HTML part = %q, want %q", parsed.HTML, msg.HTML)
+	}
+}
+
+func TestSendTextOnly(t *testing.T) {
+	m, srv := newTestMailer(t)
+
+	if err := m.Send(Message{To: "jane@example.com", Subject: "Hello", Text: "Just text.\n"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	messages := srv.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("server received %d messages, want 1", len(messages))
+	}
+	parsed, err := messages[0].Parse()
+	if err != nil {
+		t.Fatalf("parse message: %v", err)
+	}
+	if ct := parsed.Header.Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want text/plain", ct)
+	}
+	if parsed.Text != "Just text.\n" || parsed.HTML != "" {
+		t.Errorf("bodies = %q, %q, want only the text", parsed.Text, parsed.HTML)
+	}
+}
+
+func TestSendRejected(t *testing.T) {
+	m, srv := newTestMailer(t)
+	srv.Reject("451 4.3.0 Try again later")
+
+	err := m.Send(Message{To: "jane@example.com", Subject: "Hello", Text: "Hi"})
+	if err == nil || !strings.Contains(err.Error(), "Try again later") {
+		t.Fatalf("Send error = %v, want the server's rejection", err)
+	}
+	if messages := srv.Messages(); len(messages) != 0 {
+		t.Fatalf("server accepted %d messages while rejecting", len(messages))
+	}
+}
+
+func TestSendInvalidAddress(t *testing.T) {
+	m := &Mailer{Addr: "127.0.0.1:1", From: "bot@example.com"}
+	if err := m.Send(Message{To: "not an address", Text: "Hi"}); err == nil || !strings.Contains(err.Error(), "invalid recipient") {
+		t.Errorf("Send error = %v, want an invalid recipient", err)
+	}
+
+	m.From = "bot"
+	if err := m.Send(Message{To: "jane@example.com", Text: "Hi"}); err == nil || !strings.Contains(err.Error(), "invalid sender") {
+		t.Errorf("Send error = %v, want an invalid sender", err)
+	}
+}
+
+func TestSendTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	// Accept connections but never greet, like a relay that hangs.
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	m := &Mailer{Addr: ln.Addr().String(), From: "bot@example.com", Timeout: 100 * time.Millisecond}
+	start := time.Now()
+	err = m.Send(Message{To: "jane@example.com", Text: "Hi"})
+	if err == nil {
+		t.Fatal("Send to a silent relay succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Send returned after %v, want it to give up after the timeout", elapsed)
+	}
+}
@@ -0,0 +1,242 @@
+// Package mailtest provides an in-process SMTP server for tests of code that
+// sends email.
+package mailtest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+)
+
+// Message is a message accepted by the server. Data has the dot-stuffing
+// removed and LF line endings.
+type Message struct {
+	From string
+	To   []string
+	Data []byte
+}
+
+// Server is a minimal SMTP server on a loopback port that records every
+// message it accepts. It speaks just enough of the protocol for net/smtp
+// without STARTTLS or authentication.
+type Server struct {
+	Addr string
+
+	ln net.Listener
+	wg sync.WaitGroup
+
+	mu       sync.Mutex
+	reject   string
+	messages []Message
+}
+
+// NewServer starts a server. The caller should call Close when finished.
+func NewServer() *Server {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("mailtest: failed to listen on a port: %v", err))
+	}
+
+	s := &Server{Addr: ln.Addr().String(), ln: ln}
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+// Close stops accepting connections and waits for open ones to finish.
+func (s *Server) Close() {
+	s.ln.Close()
+	s.wg.Wait()
+}
+
+// Reject makes the server answer MAIL FROM with reply, e.g.
+// "451 4.3.0 Try again later". An empty reply accepts mail again.
+func (s *Server) Reject(reply string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reject = reply
+}
+
+// Messages returns the messages accepted so far.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer s.wg.Done()
+	defer conn.Close()
+
+	tp := textproto.NewConn(conn)
+	reply := func(line string) bool {
+		return tp.PrintfLine("%s", line) == nil
+	}
+
+	var msg Message
+	if !reply("220 mailtest ESMTP") {
+		return
+	}
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+		var answer string
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			answer = "250 mailtest"
+		case "MAIL":
+			s.mu.Lock()
+			answer = s.reject
+			s.mu.Unlock()
+			if answer == "" {
+				msg = Message{From: address(arg)}
+				answer = "250 2.1.0 OK"
+			}
+		case "RCPT":
+			if msg.From == "" {
+				answer = "503 5.5.1 MAIL first"
+				break
+			}
+			msg.To = append(msg.To, address(arg))
+			answer = "250 2.1.5 OK"
+		case "DATA":
+			if msg.From == "" || len(msg.To) == 0 {
+				answer = "503 5.5.1 MAIL and RCPT first"
+				break
+			}
+			if !reply("354 End data with <CR><LF>.<CR><LF>") {
+				return
+			}
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg.Data = data
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			msg = Message{}
+			answer = "250 2.0.0 OK"
+		case "RSET":
+			msg = Message{}
+			answer = "250 2.0.0 OK"
+		case "NOOP":
+			answer = "250 2.0.0 OK"
+		case "QUIT":
+			reply("221 2.0.0 Bye")
+			return
+		default:
+			answer = "502 5.5.2 Command not implemented"
+		}
+		if !reply(answer) {
+			return
+		}
+	}
+}
+
+// address extracts the mailbox from a MAIL FROM or RCPT TO argument like
+// "FROM:<jane@example.com> BODY=8BITMIME".
+func address(arg string) string {
+	start := strings.Index(arg, "<")
+	end := strings.Index(arg, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return arg[start+1 : end]
+}
+
+// Parsed is a message with its headers and bodies decoded. Text and HTML have
+// LF line endings.
+type Parsed struct {
+	Header  mail.Header
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Parse decodes a text/plain or multipart/alternative message.
+func (m Message) Parse() (*Parsed, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(m.Data))
+	if err != nil {
+		return nil, err
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		return nil, err
+	}
+	parsed := &Parsed{Header: msg.Header, Subject: subject}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	if mediaType != "multipart/alternative" {
+		err := parsed.setBody(mediaType, msg.Header.Get("Content-Transfer-Encoding"), msg.Body)
+		return parsed, err
+	}
+
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			return parsed, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		partType, _, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if err != nil {
+			return nil, err
+		}
+		// NextPart already decodes quoted-printable parts.
+		if err := parsed.setBody(partType, part.Header.Get("Content-Transfer-Encoding"), part); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *Parsed) setBody(mediaType, encoding string, r io.Reader) error {
+	if strings.EqualFold(encoding, "quoted-printable") {
+		r = quotedprintable.NewReader(r)
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	text := strings.ReplaceAll(string(body), "\r\n", "\n")
+
+	switch mediaType {
+	case "text/plain":
+		p.Text = text
+	case "text/html":
+		p.HTML = text
+	default:
+		return fmt.Errorf("mailtest: unexpected part %s", mediaType)
+	}
+	return nil
+}
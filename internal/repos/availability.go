@@ -0,0 +1,162 @@
+package repos
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+func (r *Repo) CreateAvailability(a *entities.Availability) error {
+	query := `insert into user_availability (user_id, start_date, end_date, reason, source_uid)
+				values ($1, $2, $3, $4, nullif($5, ''))
+				returning id;`
+	return r.db.QueryRow(query, a.UserID, a.StartDate, a.EndDate, a.Reason, a.SourceUID).Scan(&a.ID)
+}
+
+func (r *Repo) GetAvailability(id int64) (*entities.Availability, error) {
+	query := "select id, user_id, start_date, end_date, reason, coalesce(source_uid, '') from user_availability where id = $1;"
+	return scanAvailability(r.db.QueryRow(query, id))
+}
+
+func (r *Repo) GetUserAvailability(userID string) ([]entities.Availability, error) {
+	query := "select id, user_id, start_date, end_date, reason, coalesce(source_uid, '') from user_availability where user_id = $1 order by start_date;"
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var windows []entities.Availability
+	for rows.Next() {
+		a, err := scanAvailability(rows)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, *a)
+	}
+
+	return windows, rows.Err()
+}
+
+func (r *Repo) UpdateAvailability(a *entities.Availability) error {
+	query := "update user_availability set start_date = $1, end_date = $2, reason = $3 where id = $4;"
+	res, err := r.db.Exec(query, a.StartDate, a.EndDate, a.Reason, a.ID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *Repo) DeleteAvailability(id int64) error {
+	res, err := r.db.Exec("delete from user_availability where id = $1;", id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *Repo) GetAvailabilityBySourceUID(uid string) (*entities.Availability, error) {
+	query := "select id, user_id, start_date, end_date, reason, coalesce(source_uid, '') from user_availability where source_uid = $1;"
+	return scanAvailability(r.db.QueryRow(query, uid))
+}
+
+// ApplyAvailabilityImport writes the create, update and delete items of an
+// import in a single transaction, keyed by source_uid.
+func (r *Repo) ApplyAvailabilityImport(items []entities.AvailabilityImportItem) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, item := range items {
+		switch item.Action {
+		case entities.ImportActionCreate, entities.ImportActionUpdate:
+			query := `insert into user_availability (user_id, start_date, end_date, reason, source_uid)
+						values ($1, $2, $3, $4, $5)
+						on conflict (source_uid)
+						do update set
+						user_id = excluded.user_id,
+						start_date = excluded.start_date,
+						end_date = excluded.end_date,
+						reason = excluded.reason;`
+			_, err = tx.Exec(query, item.UserID, item.StartDate, item.EndDate, item.Reason, item.SourceUID)
+		case entities.ImportActionDelete:
+			_, err = tx.Exec("delete from user_availability where source_uid = $1;", item.SourceUID)
+		default:
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetUnavailableAssignments returns reviewer assignments on OPEN pull requests
+// whose reviewer has an availability window covering the current date.
+func (r *Repo) GetUnavailableAssignments() ([]entities.ReviewAssignment, error) {
+	query := `
+		select prr.pull_request_id, prr.user_id, prr.assigned_at
+		from pr_reviewers prr
+		join pull_requests pr on pr.id = prr.pull_request_id
+		where pr.status = 'OPEN'
+		and exists (
+			select 1 from user_availability ua
+			where ua.user_id = prr.user_id and current_date between ua.start_date and ua.end_date
+		)
+		order by prr.assigned_at;
+	`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var assignments []entities.ReviewAssignment
+	for rows.Next() {
+		var a entities.ReviewAssignment
+		if err := rows.Scan(&a.PullRequestID, &a.UserID, &a.AssignedAt); err != nil {
+			return nil, err
+		}
+		assignments = append(assignments, a)
+	}
+
+	return assignments, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAvailability(row rowScanner) (*entities.Availability, error) {
+	var a entities.Availability
+	var start, end time.Time
+	if err := row.Scan(&a.ID, &a.UserID, &start, &end, &a.Reason, &a.SourceUID); err != nil {
+		return nil, err
+	}
+	a.StartDate = start.Format(entities.DateLayout)
+	a.EndDate = end.Format(entities.DateLayout)
+
+	return &a, nil
+}
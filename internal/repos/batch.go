@@ -0,0 +1,126 @@
+package repos
+
+import (
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+	"github.com/lib/pq"
+)
+
+// The queries below load rows for many keys at once so that nested GraphQL
+// fields cost one query per level rather than one per parent.
+
+func (r *Repo) GetUsersByIDs(ids []string) ([]entities.User, error) {
+	query := `select id, username, team_name, is_active, max_open_reviews, review_weight, email
+				from users where id = any($1);`
+	return r.queryUsers(query, pq.Array(ids))
+}
+
+// GetMembersOfTeams returns the members of several teams ordered by team and
+// username.
+func (r *Repo) GetMembersOfTeams(teamNames []string) ([]entities.User, error) {
+	query := `select id, username, team_name, is_active, max_open_reviews, review_weight, email
+				from users where team_name = any($1)
+				order by team_name, username;`
+	return r.queryUsers(query, pq.Array(teamNames))
+}
+
+func (r *Repo) queryUsers(query string, args ...interface{}) ([]entities.User, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []entities.User
+	for rows.Next() {
+		var user entities.User
+		err := rows.Scan(&user.ID, &user.Username, &user.TeamName, &user.IsActive, &user.MaxOpenReviews, &user.ReviewWeight, &user.Email)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+func (r *Repo) GetPullRequestsByIDs(ids []string) ([]entities.PullRequest, error) {
+	query := `select id, name, author_id, status, created_at, merged_at, closed_at, labels
+				from pull_requests where id = any($1);`
+	rows, err := r.db.Query(query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prs []entities.PullRequest
+	for rows.Next() {
+		var pr entities.PullRequest
+		if err := rows.Scan(&pr.ID, &pr.Name, &pr.AuthorID, &pr.Status, &pr.CreatedAt, &pr.MergedAt, &pr.ClosedAt, pq.Array(&pr.Labels)); err != nil {
+			return nil, err
+		}
+		prs = append(prs, pr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	found := make([]string, len(prs))
+	for i, pr := range prs {
+		found[i] = pr.ID
+	}
+	reviewers, err := r.GetReviewersForPRs(found)
+	if err != nil {
+		return nil, err
+	}
+	for i := range prs {
+		prs[i].AssignedReviewers = reviewers[prs[i].ID]
+		if prs[i].AssignedReviewers == nil {
+			prs[i].AssignedReviewers = []string{}
+		}
+		if prs[i].Labels == nil {
+			prs[i].Labels = []string{}
+		}
+	}
+
+	return prs, nil
+}
+
+// GetAssignmentsForUsers returns the review assignments of several users
+// ordered by assignment time. An empty status or StatusAll matches every
+// pull request status.
+func (r *Repo) GetAssignmentsForUsers(userIDs []string, status string) ([]entities.ReviewAssignment, error) {
+	query := `select prr.pull_request_id, prr.user_id, prr.assigned_at, coalesce(prr.verdict, '')
+				from pr_reviewers prr
+				join pull_requests pr on pr.id = prr.pull_request_id
+				where prr.user_id = any($1)
+				and ($2 = '' or $2 = 'ALL' or pr.status = $2)
+				order by prr.assigned_at, prr.pull_request_id;`
+	return r.queryAssignments(query, pq.Array(userIDs), status)
+}
+
+func (r *Repo) GetAssignmentsForPRs(prIDs []string) ([]entities.ReviewAssignment, error) {
+	query := `select pull_request_id, user_id, assigned_at, coalesce(verdict, '')
+				from pr_reviewers
+				where pull_request_id = any($1)
+				order by assigned_at;`
+	return r.queryAssignments(query, pq.Array(prIDs))
+}
+
+func (r *Repo) queryAssignments(query string, args ...interface{}) ([]entities.ReviewAssignment, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var assignments []entities.ReviewAssignment
+	for rows.Next() {
+		var a entities.ReviewAssignment
+		if err := rows.Scan(&a.PullRequestID, &a.UserID, &a.AssignedAt, &a.Verdict); err != nil {
+			return nil, err
+		}
+		assignments = append(assignments, a)
+	}
+
+	return assignments, rows.Err()
+}
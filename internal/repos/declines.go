@@ -0,0 +1,121 @@
+package repos
+
+import (
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+// GetPRDecliners returns users who declined to review the PR.
+func (r *Repo) GetPRDecliners(prID string) ([]string, error) {
+	rows, err := r.db.Query("select user_id from pr_declines where pull_request_id = $1;", prID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		users = append(users, userID)
+	}
+
+	return users, rows.Err()
+}
+
+// DeclineReviewer records the decline, removes the reviewer and, when
+// newUserID is not empty, assigns the replacement in one transaction.
+func (r *Repo) DeclineReviewer(prID, userID, reason, comment, newUserID string, events ...entities.PREvent) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := "insert into pr_declines (pull_request_id, user_id, reason, comment) values ($1, $2, $3, $4);"
+	if _, err := tx.Exec(query, prID, userID, reason, comment); err != nil {
+		return err
+	}
+
+	query = "delete from pr_reviewers where pull_request_id = $1 and user_id = $2;"
+	if _, err := tx.Exec(query, prID, userID); err != nil {
+		return err
+	}
+
+	if newUserID != "" {
+		query = "insert into pr_reviewers (pull_request_id, user_id) values ($1, $2);"
+		if _, err := tx.Exec(query, prID, newUserID); err != nil {
+			return err
+		}
+	}
+
+	if err := r.insertEvents(tx, events); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetReviewerStats returns review counters of users, optionally narrowed to
+// one team and/or one user.
+func (r *Repo) GetReviewerStats(teamName, userID string) ([]entities.ReviewerStats, error) {
+	query := `
+		select u.id, u.username, u.team_name,
+			(select count(*) from pr_reviewers prr
+				join pull_requests pr on pr.id = prr.pull_request_id
+				where prr.user_id = u.id and pr.status = 'OPEN'),
+			(select count(*) from pr_reviewers prr where prr.user_id = u.id)
+		from users u
+		where ($1 = '' or u.team_name = $1) and ($2 = '' or u.id = $2)
+		order by u.team_name, u.username;
+	`
+	rows, err := r.db.Query(query, teamName, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []entities.ReviewerStats
+	index := make(map[string]int)
+	for rows.Next() {
+		st := entities.ReviewerStats{Declines: make(map[string]int)}
+		if err := rows.Scan(&st.UserID, &st.Username, &st.TeamName, &st.OpenReviews, &st.TotalReviews); err != nil {
+			return nil, err
+		}
+		for _, reason := range entities.DeclineReasons {
+			st.Declines[reason] = 0
+		}
+		index[st.UserID] = len(stats)
+		stats = append(stats, st)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query = `
+		select d.user_id, d.reason, count(*)
+		from pr_declines d
+		join users u on u.id = d.user_id
+		where ($1 = '' or u.team_name = $1) and ($2 = '' or u.id = $2)
+		group by d.user_id, d.reason;
+	`
+	declineRows, err := r.db.Query(query, teamName, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer declineRows.Close()
+
+	for declineRows.Next() {
+		var id, reason string
+		var count int
+		if err := declineRows.Scan(&id, &reason, &count); err != nil {
+			return nil, err
+		}
+		if i, ok := index[id]; ok {
+			stats[i].Declines[reason] = count
+		}
+	}
+
+	return stats, declineRows.Err()
+}
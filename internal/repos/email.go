@@ -0,0 +1,67 @@
+package repos
+
+import (
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+	"github.com/lib/pq"
+)
+
+func (r *Repo) GetEmailPreferences(userID string) (*entities.EmailPreferences, error) {
+	prefs := entities.EmailPreferences{UserID: userID}
+	query := "select email_opt_out from users where id = $1;"
+	if err := r.db.QueryRow(query, userID).Scan(pq.Array(&prefs.OptOut)); err != nil {
+		return nil, err
+	}
+	if prefs.OptOut == nil {
+		prefs.OptOut = []string{}
+	}
+	return &prefs, nil
+}
+
+func (r *Repo) SetEmailPreferences(prefs *entities.EmailPreferences, events ...entities.PREvent) error {
+	query := "update users set email_opt_out = $1 where id = $2;"
+	return r.execWithEvents(query, []interface{}{pq.Array(prefs.OptOut), prefs.UserID}, events)
+}
+
+// GetEmailDigestCandidates returns the work schedules of active users with an
+// email address who did not opt out of the digest and have open reviews
+// without a verdict.
+func (r *Repo) GetEmailDigestCandidates() ([]entities.WorkSchedule, error) {
+	query := "select " + workScheduleColumns + ` from users u
+		where u.email <> '' and u.is_active and not ('digest' = any(u.email_opt_out))
+		and exists (
+			select 1 from pr_reviewers prr
+			join pull_requests pr on pr.id = prr.pull_request_id
+			where prr.user_id = u.id and pr.status = 'OPEN' and prr.verdict is null
+		);`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []entities.WorkSchedule
+	for rows.Next() {
+		ws, err := scanWorkSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, *ws)
+	}
+
+	return schedules, rows.Err()
+}
+
+// EnqueueEmailDigest queues the digest of a user for date unless it was
+// queued already, and reports whether it was.
+func (r *Repo) EnqueueEmailDigest(userID, teamName, date string) (bool, error) {
+	query := `insert into notifications (channel, kind, team_name, user_id, digest_date)
+				values ('email', 'digest', $1, $2, $3)
+				on conflict do nothing;`
+	res, err := r.db.Exec(query, teamName, userID, date)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	return rows > 0, err
+}
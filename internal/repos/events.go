@@ -0,0 +1,222 @@
+package repos
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+	"github.com/lib/pq"
+)
+
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// emailTopic returns the user an event should be emailed to and the topic
+// they may have opted out of, or empty strings.
+func emailTopic(e entities.PREvent) (string, string) {
+	switch e.Type {
+	case entities.EventReviewerAssigned, entities.EventReviewerAdded:
+		return e.NewUserID, entities.EmailAssignment
+	case entities.EventReviewerReassigned:
+		return e.NewUserID, entities.EmailReassignment
+	case entities.EventSLABreached:
+		return e.UserID, entities.EmailSLABreach
+	}
+	return "", ""
+}
+
+// SetEmailEnabled controls whether event writes queue emails. It is off until
+// a mailer is configured, so emails nobody would send do not pile up.
+func (r *Repo) SetEmailEnabled(enabled bool) {
+	r.email = enabled
+}
+
+// insertEvents records events and, acting as the outbox, queues a delivery
+// for every active subscription matching each event, a chat notification for
+// teams with a chat webhook and, with email enabled, an email to the affected
+// user, so all of them commit or roll back together with the change they
+// describe.
+//
+// ex must be a transaction that commits right after. It holds a lock on the
+// event log until then, so event ids become visible in order and readers
+// paging by id never step over an event that commits later.
+func (r *Repo) insertEvents(ex execer, events []entities.PREvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	if _, err := ex.Exec("select pg_advisory_xact_lock('pr_events'::regclass::oid::bigint);"); err != nil {
+		return err
+	}
+
+	query := `with e as (
+					insert into pr_events
+					(event_type, pull_request_id, user_id, old_user_id, new_user_id, team_name, reason, actor, details)
+					values ($1, nullif($2, ''), nullif($3, ''), nullif($4, ''), nullif($5, ''), nullif($6, ''), nullif($7, ''), nullif($8, ''), $9)
+					returning id, event_type, team_name
+				), d as (
+					insert into webhook_deliveries (subscription_id, event_id)
+					select s.id, e.id from e
+					join webhook_subscriptions s on s.is_active
+						and (cardinality(s.event_types) = 0 or e.event_type = any(s.event_types))
+						and (s.team_name is null or s.team_name = e.team_name)
+				), c as (
+					insert into notifications (channel, kind, team_name, event_id)
+					select 'chat', 'event', t.team_name, e.id from e
+					join teams t on t.team_name = e.team_name and t.chat_webhook_url <> ''
+					where e.event_type = any($10)
+				)
+				insert into notifications (channel, kind, team_name, user_id, event_id)
+				select 'email', 'event', u.team_name, u.id, e.id from e
+				join users u on u.id = $11 and u.email <> '' and not ($12 = any(u.email_opt_out));`
+	for _, e := range events {
+		var details []byte
+		if len(e.Details) > 0 {
+			var err error
+			details, err = json.Marshal(e.Details)
+			if err != nil {
+				return err
+			}
+		}
+		var recipient, topic string
+		if r.email {
+			recipient, topic = emailTopic(e)
+		}
+		_, err := ex.Exec(query, e.Type, e.PullRequestID, e.UserID, e.OldUserID, e.NewUserID, e.TeamName, e.Reason, e.Actor, details,
+			pq.Array(entities.ChatEventTypes), recipient, topic)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// execWithEvents runs a single-row mutation and records its events in the
+// same transaction. It returns sql.ErrNoRows when nothing was changed.
+func (r *Repo) execWithEvents(query string, args []interface{}, events []entities.PREvent) error {
+	return r.execChange(query, args, events, false)
+}
+
+// execSCMChange is execWithEvents for writes that finish an SCM change; they
+// also record the bound delivery.
+func (r *Repo) execSCMChange(query string, args []interface{}, events []entities.PREvent) error {
+	return r.execChange(query, args, events, true)
+}
+
+func (r *Repo) execChange(query string, args []interface{}, events []entities.PREvent, recordDelivery bool) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if recordDelivery {
+		if err := r.recordDelivery(tx); err != nil {
+			return err
+		}
+	}
+
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	if err := r.insertEvents(tx, events); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+const eventColumns = `id, event_type, coalesce(pull_request_id, ''), coalesce(user_id, ''),
+				coalesce(old_user_id, ''), coalesce(new_user_id, ''), coalesce(team_name, ''),
+				coalesce(reason, ''), coalesce(actor, ''), details, created_at`
+
+func (r *Repo) GetPRHistory(prID string) ([]entities.PREvent, error) {
+	query := "select " + eventColumns + " from pr_events where pull_request_id = $1 order by id;"
+	return r.queryEvents(query, prID)
+}
+
+// ListEvents returns events matching the filter in id order, starting after
+// filter.AfterID. A user filter matches the subject, old and new reviewer;
+// Since and Until are compared in UTC.
+func (r *Repo) ListEvents(filter entities.EventFilter) ([]entities.PREvent, error) {
+	query := "select " + eventColumns + " from pr_events where id > $1"
+	args := []interface{}{filter.AfterID}
+
+	add := func(cond string, value interface{}) {
+		args = append(args, value)
+		query += fmt.Sprintf(" and "+cond, len(args))
+	}
+	if filter.Type != "" {
+		add("event_type = $%d", filter.Type)
+	}
+	if len(filter.Types) > 0 {
+		add("event_type = any($%d)", pq.Array(filter.Types))
+	}
+	if filter.PullRequestID != "" {
+		add("pull_request_id = $%d", filter.PullRequestID)
+	}
+	if filter.UserID != "" {
+		add("$%[1]d in (user_id, old_user_id, new_user_id)", filter.UserID)
+	}
+	if filter.TeamName != "" {
+		add("team_name = $%d", filter.TeamName)
+	}
+	if filter.Actor != "" {
+		add("actor = $%d", filter.Actor)
+	}
+	if filter.Since != nil {
+		add("created_at >= $%d", filter.Since.UTC())
+	}
+	if filter.Until != nil {
+		add("created_at < $%d", filter.Until.UTC())
+	}
+
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" order by id limit $%d;", len(args))
+
+	return r.queryEvents(query, args...)
+}
+
+func (r *Repo) LatestEventID() (int64, error) {
+	var id int64
+	err := r.db.QueryRow("select coalesce(max(id), 0) from pr_events;").Scan(&id)
+	return id, err
+}
+
+func (r *Repo) queryEvents(query string, args ...interface{}) ([]entities.PREvent, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []entities.PREvent
+	for rows.Next() {
+		var e entities.PREvent
+		var details []byte
+		err := rows.Scan(&e.ID, &e.Type, &e.PullRequestID, &e.UserID, &e.OldUserID, &e.NewUserID,
+			&e.TeamName, &e.Reason, &e.Actor, &details, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if len(details) > 0 {
+			if err := json.Unmarshal(details, &e.Details); err != nil {
+				return nil, err
+			}
+		}
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
@@ -0,0 +1,181 @@
+package repos
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+	"github.com/lib/pq"
+)
+
+func (r *Repo) GetChatSettings(teamName string) (*entities.ChatSettings, error) {
+	settings := entities.ChatSettings{TeamName: teamName}
+	query := "select chat_webhook_url, chat_template, to_char(chat_digest_at, 'HH24:MI') from teams where team_name = $1;"
+	err := r.db.QueryRow(query, teamName).Scan(&settings.WebhookURL, &settings.Template, &settings.DigestAt)
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (r *Repo) SetChatSettings(settings *entities.ChatSettings, events ...entities.PREvent) error {
+	query := "update teams set chat_webhook_url = $1, chat_template = $2, chat_digest_at = $3 where team_name = $4;"
+	args := []interface{}{settings.WebhookURL, settings.Template, settings.DigestAt, settings.TeamName}
+	return r.execWithEvents(query, args, events)
+}
+
+func (r *Repo) SetChatMention(userID, mention string, events ...entities.PREvent) error {
+	query := "update users set chat_mention = $1, updated_at = $2 where id = $3;"
+	return r.execWithEvents(query, []interface{}{mention, time.Now().UTC(), userID}, events)
+}
+
+// GetChatMentions returns how to mention each of the given users in chat:
+// their chat mention if set, their username otherwise.
+func (r *Repo) GetChatMentions(userIDs []string) (map[string]string, error) {
+	query := "select id, coalesce(nullif(chat_mention, ''), username) from users where id = any($1);"
+	rows, err := r.db.Query(query, pq.Array(userIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mentions := make(map[string]string)
+	for rows.Next() {
+		var id, mention string
+		if err := rows.Scan(&id, &mention); err != nil {
+			return nil, err
+		}
+		mentions[id] = mention
+	}
+
+	return mentions, rows.Err()
+}
+
+// EnqueueChatDigests queues today's digest for every team with a chat
+// webhook whose digest time has passed. Each team gets at most one digest a
+// day.
+func (r *Repo) EnqueueChatDigests(now time.Time) (int, error) {
+	query := `
+		insert into notifications (channel, kind, team_name, digest_date)
+		select 'chat', 'digest', team_name, $1::date from teams
+		where chat_webhook_url <> '' and chat_digest_at is not null and chat_digest_at <= $2::time
+		on conflict do nothing;
+	`
+	now = now.UTC()
+	res, err := r.db.Exec(query, now.Format(entities.DateLayout), now.Format("15:04"))
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := res.RowsAffected()
+	return int(rows), err
+}
+
+const notificationColumns = `id, channel, kind, team_name, coalesce(user_id, ''), event_id, to_char(digest_date, 'YYYY-MM-DD'),
+				status, attempts, next_attempt_at, last_error, sent_at, created_at`
+
+func scanNotification(row rowScanner) (*entities.Notification, error) {
+	var n entities.Notification
+	err := row.Scan(&n.ID, &n.Channel, &n.Kind, &n.TeamName, &n.UserID, &n.EventID, &n.DigestDate,
+		&n.Status, &n.Attempts, &n.NextAttemptAt, &n.LastError, &n.SentAt, &n.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+func (r *Repo) queryNotifications(query string, args ...interface{}) ([]entities.Notification, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []entities.Notification
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, *n)
+	}
+
+	return notifications, rows.Err()
+}
+
+func (r *Repo) GetNotification(id int64) (*entities.Notification, error) {
+	query := "select " + notificationColumns + " from notifications where id = $1;"
+	return scanNotification(r.db.QueryRow(query, id))
+}
+
+// ListNotifications returns notifications matching the filter in id order,
+// starting after filter.AfterID.
+func (r *Repo) ListNotifications(filter entities.NotificationFilter) ([]entities.Notification, error) {
+	query := "select " + notificationColumns + " from notifications where id > $1"
+	args := []interface{}{filter.AfterID}
+
+	if filter.Channel != "" {
+		args = append(args, filter.Channel)
+		query += fmt.Sprintf(" and channel = $%d", len(args))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		query += fmt.Sprintf(" and status = $%d", len(args))
+	}
+
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" order by id limit $%d;", len(args))
+
+	return r.queryNotifications(query, args...)
+}
+
+// ClaimDueNotifications picks up to limit pending notifications of the given
+// channels whose next attempt is due and leases them like ClaimDueDeliveries
+// does.
+func (r *Repo) ClaimDueNotifications(channels []string, limit int, lease time.Duration) ([]entities.Notification, error) {
+	query := `
+		with due as (
+			select id from notifications
+			where status = 'PENDING' and next_attempt_at <= localtimestamp and channel = any($3)
+			order by next_attempt_at, id
+			limit $1
+			for update skip locked
+		)
+		update notifications n
+		set next_attempt_at = localtimestamp + make_interval(secs => $2)
+		from due
+		where n.id = due.id
+		returning ` + notificationColumns + `;
+	`
+	return r.queryNotifications(query, limit, lease.Seconds(), pq.Array(channels))
+}
+
+func (r *Repo) MarkNotificationSent(id int64) error {
+	query := `update notifications
+				set status = 'SENT', attempts = attempts + 1, last_error = '', sent_at = localtimestamp
+				where id = $1;`
+	return r.execWithEvents(query, []interface{}{id}, nil)
+}
+
+// MarkNotificationFailed records a failed attempt. A nil retryIn moves the
+// notification to the dead-letter state.
+func (r *Repo) MarkNotificationFailed(id int64, errMsg string, retryIn *time.Duration) error {
+	if retryIn == nil {
+		query := "update notifications set status = 'DEAD', attempts = attempts + 1, last_error = $1 where id = $2;"
+		return r.execWithEvents(query, []interface{}{errMsg, id}, nil)
+	}
+
+	query := `update notifications
+				set attempts = attempts + 1, last_error = $1, next_attempt_at = localtimestamp + make_interval(secs => $2)
+				where id = $3;`
+	return r.execWithEvents(query, []interface{}{errMsg, retryIn.Seconds(), id}, nil)
+}
+
+// RequeueNotification schedules a notification to be sent again right away
+// with a fresh retry budget.
+func (r *Repo) RequeueNotification(id int64) error {
+	query := `update notifications
+				set status = 'PENDING', attempts = 0, next_attempt_at = localtimestamp, sent_at = null
+				where id = $1;`
+	return r.execWithEvents(query, []interface{}{id}, nil)
+}
@@ -0,0 +1,140 @@
+package repos
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+	"github.com/lib/pq"
+)
+
+// ListPullRequests returns pull requests matching the filter ordered by
+// (created_at, id), continuing after the keyset given by AfterCreatedAt and
+// AfterID. Time bounds may carry any offset; the columns hold UTC.
+func (r *Repo) ListPullRequests(filter entities.PRFilter) ([]entities.PullRequest, error) {
+	query := `select pr.id, pr.name, pr.author_id, pr.status, pr.created_at, pr.merged_at, pr.closed_at, pr.labels
+				from pull_requests pr
+				where true`
+	var args []interface{}
+
+	add := func(cond string, value interface{}) {
+		args = append(args, value)
+		query += " and " + strings.ReplaceAll(cond, "?", fmt.Sprintf("$%d", len(args)))
+	}
+	if filter.Status != "" {
+		add("pr.status = ?", filter.Status)
+	}
+	if filter.AuthorID != "" {
+		add("pr.author_id = ?", filter.AuthorID)
+	}
+	if filter.ReviewerID != "" {
+		add("exists (select 1 from pr_reviewers prr where prr.pull_request_id = pr.id and prr.user_id = ?)", filter.ReviewerID)
+	}
+	if filter.TeamName != "" {
+		add("pr.author_id in (select id from users where team_name = ?)", filter.TeamName)
+	}
+	if filter.NameContains != "" {
+		add("pr.name ilike '%' || ? || '%'", escapeLike(filter.NameContains))
+	}
+	if filter.CreatedFrom != nil {
+		add("pr.created_at >= ?", filter.CreatedFrom.UTC())
+	}
+	if filter.CreatedTo != nil {
+		add("pr.created_at < ?", filter.CreatedTo.UTC())
+	}
+	if filter.MergedFrom != nil {
+		add("pr.merged_at >= ?", filter.MergedFrom.UTC())
+	}
+	if filter.MergedTo != nil {
+		add("pr.merged_at < ?", filter.MergedTo.UTC())
+	}
+
+	order := "desc"
+	cmp := "<"
+	if filter.Ascending {
+		order, cmp = "asc", ">"
+	}
+	if filter.AfterCreatedAt != nil {
+		args = append(args, filter.AfterCreatedAt.UTC(), filter.AfterID)
+		query += fmt.Sprintf(" and (pr.created_at, pr.id) %s ($%d, $%d)", cmp, len(args)-1, len(args))
+	}
+
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" order by pr.created_at %s, pr.id %s limit $%d;", order, order, len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prs []entities.PullRequest
+	for rows.Next() {
+		var pr entities.PullRequest
+		if err := rows.Scan(&pr.ID, &pr.Name, &pr.AuthorID, &pr.Status, &pr.CreatedAt, &pr.MergedAt, &pr.ClosedAt, pq.Array(&pr.Labels)); err != nil {
+			return nil, err
+		}
+		prs = append(prs, pr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(prs))
+	for i, pr := range prs {
+		ids[i] = pr.ID
+	}
+	reviewers, err := r.GetReviewersForPRs(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range prs {
+		prs[i].AssignedReviewers = reviewers[prs[i].ID]
+		if prs[i].AssignedReviewers == nil {
+			prs[i].AssignedReviewers = []string{}
+		}
+		if prs[i].Labels == nil {
+			prs[i].Labels = []string{}
+		}
+	}
+
+	return prs, nil
+}
+
+// GetReviewersForPRs loads reviewers of several pull requests in one query.
+func (r *Repo) GetReviewersForPRs(prIDs []string) (map[string][]string, error) {
+	result := make(map[string][]string, len(prIDs))
+	if len(prIDs) == 0 {
+		return result, nil
+	}
+
+	placeholders := make([]string, len(prIDs))
+	args := make([]interface{}, len(prIDs))
+	for i, id := range prIDs {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+
+	query := fmt.Sprintf(`select pull_request_id, user_id from pr_reviewers
+				where pull_request_id in (%s)
+				order by assigned_at;`, strings.Join(placeholders, ", "))
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var prID, userID string
+		if err := rows.Scan(&prID, &userID); err != nil {
+			return nil, err
+		}
+		result[prID] = append(result[prID], userID)
+	}
+
+	return result, rows.Err()
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
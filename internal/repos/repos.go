@@ -6,20 +6,22 @@ import (
 	"time"
 
 	"github.com/alexalexbor04/pull_request_service/internal/entities"
+	"github.com/lib/pq"
 )
 
 type Repo struct {
-	db *sql.DB
+	db       *sql.DB
+	delivery *inboundDelivery
+	email    bool
 }
 
 func New(db *sql.DB) *Repo { 
 	return &Repo{db: db}
 }
 
-func (r *Repo) CreateTeam(teamName string) error { 
+func (r *Repo) CreateTeam(teamName string, events ...entities.PREvent) error { 
 	query := "insert into teams (team_name) values ($1);"
-	_, err := r.db.Exec(query, teamName)
-	return err
+	return r.execWithEvents(query, []interface{}{teamName}, events)
 }
 
 func (r *Repo) GetTeamMembers(teamName string) ([]entities.User, error) {
@@ -80,7 +82,7 @@ func (r *Repo) TeamExists(teamName string) (bool, error) {
 	return exists, err
 }
 
-func (r *Repo) CreateOrUpdateUser(user *entities.User) error {
+func (r *Repo) CreateOrUpdateUser(user *entities.User, events ...entities.PREvent) error {
 	query := `insert into users (id, username, team_name, is_active, updated_at) 
 				values ($1, $2, $3, $4, $5)
 				on conflict (id)
@@ -89,14 +91,13 @@ func (r *Repo) CreateOrUpdateUser(user *entities.User) error {
 				team_name = excluded.team_name,
 				is_active = excluded.is_active,
 				updated_at = excluded.updated_at;`
-	_, err := r.db.Exec(query, user.ID, user.Username, user.TeamName, user.IsActive, time.Now())
-	return err
+	return r.execWithEvents(query, []interface{}{user.ID, user.Username, user.TeamName, user.IsActive, time.Now().UTC()}, events)
 }
 
 func (r *Repo) GetUser(id string) (*entities.User, error) {
 	var user entities.User
-	query := "select id, username, team_name, is_active from users where id = $1;"
-	err := r.db.QueryRow(query, id).Scan(&user.ID, &user.Username, &user.TeamName, &user.IsActive)
+	query := "select id, username, team_name, is_active, max_open_reviews, review_weight, email from users where id = $1;"
+	err := r.db.QueryRow(query, id).Scan(&user.ID, &user.Username, &user.TeamName, &user.IsActive, &user.MaxOpenReviews, &user.ReviewWeight, &user.Email)
 	if err != nil {
 		return nil, err
 	}
@@ -104,42 +105,51 @@ func (r *Repo) GetUser(id string) (*entities.User, error) {
 	return &user, nil
 }	
 
-func (r *Repo) SetUserActive(id string, isActive bool) error {
+func (r *Repo) SetUserActive(id string, isActive bool, events ...entities.PREvent) error {
 	query := "update users set is_active = $1, updated_at = $2 where id = $3;"
-	res, err := r.db.Exec(query, isActive, time.Now(), id)
-	if err != nil {
-		return err
-	}
+	return r.execWithEvents(query, []interface{}{isActive, time.Now().UTC(), id}, events)
+}
 
-	rows, err := res.RowsAffected()
-	if err != nil {
-		return err	
-	}
+const (
+	availableToday = `not exists (
+					select 1 from user_availability ua
+					where ua.user_id = users.id and current_date between ua.start_date and ua.end_date
+				)`
+	openReviewCount = `(select count(*) from pr_reviewers prr
+					join pull_requests pr on pr.id = prr.pull_request_id
+					where prr.user_id = users.id and pr.status = 'OPEN')`
+)
 
-	if rows == 0 {
-		return sql.ErrNoRows
+func excludeClause(excludeUser []string, firstArg int) (string, []interface{}) {
+	if len(excludeUser) == 0 {
+		return "", nil
 	}
 
-	return nil
+	placeholders := ""
+	args := make([]interface{}, 0, len(excludeUser))
+	for i, id := range excludeUser {
+		if i > 0 {
+			placeholders += ", "
+		}
+		placeholders += fmt.Sprintf("$%d", i+firstArg)
+		args = append(args, id)
+	}
+	return fmt.Sprintf(" and id not in (%s)", placeholders), args
 }
 
+// GetActiveTeamMembers returns review candidates: active, available today and
+// below their max_open_reviews limit.
 func (r *Repo) GetActiveTeamMembers(teamName string, excludeUser []string) ([]entities.User, error) {
-	query := "select id, username, team_name, is_active from users where team_name = $1 and is_active = true"
+	query := `select id, username, team_name, is_active, max_open_reviews, review_weight, email from users
+				where team_name = $1 and is_active = true
+				and ` + availableToday + `
+				and (max_open_reviews is null or ` + openReviewCount + ` < max_open_reviews)`
 
 	args := []interface{}{teamName}
 
-	if len(excludeUser) > 0 {
-		placeholders := ""
-		for i, id := range excludeUser {
-			if i > 0 {
-				placeholders += ", "
-			}
-			placeholders += fmt.Sprintf("$%d", i+2)
-			args = append(args, id)
-		}
-		query += fmt.Sprintf(" and id not in (%s)", placeholders)
-	}
-	query += ";"
+	clause, excludeArgs := excludeClause(excludeUser, 2)
+	query += clause + ";"
+	args = append(args, excludeArgs...)
 
 	rows, err := r.db.Query(query, args...)
 	if err != nil {
@@ -150,7 +160,7 @@ func (r *Repo) GetActiveTeamMembers(teamName string, excludeUser []string) ([]en
 	var users []entities.User
 	for rows.Next() {
 		var user entities.User
-		if err := rows.Scan(&user.ID, &user.Username, &user.TeamName, &user.IsActive); err != nil {
+		if err := rows.Scan(&user.ID, &user.Username, &user.TeamName, &user.IsActive, &user.MaxOpenReviews, &user.ReviewWeight, &user.Email); err != nil {
 			return nil, err
 		}
 		users = append(users, user)
@@ -159,15 +169,76 @@ func (r *Repo) GetActiveTeamMembers(teamName string, excludeUser []string) ([]en
 	return users, rows.Err()
 }
 
-func (r *Repo) CreatePullRequest(pr *entities.PullRequest, revIds []string) error {
+// CountTeamMembersAtCapacity counts active, available members that are only
+// excluded from selection because of their max_open_reviews limit.
+func (r *Repo) CountTeamMembersAtCapacity(teamName string, excludeUser []string) (int, error) {
+	query := `select count(*) from users
+				where team_name = $1 and is_active = true
+				and ` + availableToday + `
+				and max_open_reviews is not null and ` + openReviewCount + ` >= max_open_reviews`
+
+	args := []interface{}{teamName}
+
+	clause, excludeArgs := excludeClause(excludeUser, 2)
+	query += clause + ";"
+	args = append(args, excludeArgs...)
+
+	var count int
+	err := r.db.QueryRow(query, args...).Scan(&count)
+	return count, err
+}
+
+func (r *Repo) SetUserMaxOpenReviews(id string, maxOpenReviews *int, events ...entities.PREvent) error {
+	query := "update users set max_open_reviews = $1, updated_at = $2 where id = $3;"
+	return r.execWithEvents(query, []interface{}{maxOpenReviews, time.Now().UTC(), id}, events)
+}
+
+func (r *Repo) SetUserReviewWeight(id string, weight float64, events ...entities.PREvent) error {
+	query := "update users set review_weight = $1, updated_at = $2 where id = $3;"
+	return r.execWithEvents(query, []interface{}{weight, time.Now().UTC(), id}, events)
+}
+
+func (r *Repo) GetTeamCapacity(teamName string) ([]entities.MemberCapacity, error) {
+	query := `select id, username, is_active, max_open_reviews, ` + openReviewCount + `
+				from users where team_name = $1 order by username;`
+	rows, err := r.db.Query(query, teamName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []entities.MemberCapacity
+	for rows.Next() {
+		var m entities.MemberCapacity
+		if err := rows.Scan(&m.UserID, &m.Username, &m.IsActive, &m.MaxOpenReviews, &m.OpenReviews); err != nil {
+			return nil, err
+		}
+		if m.MaxOpenReviews != nil {
+			remaining := *m.MaxOpenReviews - m.OpenReviews
+			if remaining < 0 {
+				remaining = 0
+			}
+			m.Remaining = &remaining
+		}
+		members = append(members, m)
+	}
+
+	return members, rows.Err()
+}
+
+func (r *Repo) CreatePullRequest(pr *entities.PullRequest, revIds []string, events ...entities.PREvent) error {
 	tx, err := r.db.Begin()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
+	if err := r.recordDelivery(tx); err != nil {
+		return err
+	}
+
 	query := "insert into pull_requests (id, name, author_id, status, created_at) values ($1, $2, $3, $4, $5);"
-	_, err = tx.Exec(query, pr.ID, pr.Name, pr.AuthorID, pr.Status, time.Now())
+	_, err = tx.Exec(query, pr.ID, pr.Name, pr.AuthorID, pr.Status, time.Now().UTC())
 	if err != nil {
 		return err
 	}
@@ -179,13 +250,17 @@ func (r *Repo) CreatePullRequest(pr *entities.PullRequest, revIds []string) erro
 			return err
 		}
 	}
+
+	if err := r.insertEvents(tx, events); err != nil {
+		return err
+	}
 	return tx.Commit()
 }
 
 func (r *Repo) GetPullRequest(prID string) (*entities.PullRequest, error) {
 	var pr entities.PullRequest
 
-	query := "select id, name, author_id, status, created_at, merged_at from pull_requests where id = $1;"
+	query := "select id, name, author_id, status, created_at, merged_at, closed_at, labels from pull_requests where id = $1;"
 	err := r.db.QueryRow(query, prID).Scan(
 		&pr.ID,
 		&pr.Name,
@@ -193,10 +268,15 @@ func (r *Repo) GetPullRequest(prID string) (*entities.PullRequest, error) {
 		&pr.Status,
 		&pr.CreatedAt,
 		&pr.MergedAt,
+		&pr.ClosedAt,
+		pq.Array(&pr.Labels),
 	)
 	if err != nil {
 		return nil, err
 	}
+	if pr.Labels == nil {
+		pr.Labels = []string{}
+	}
 
 	reviewers, err := r.GetPRReviewers(prID)
 	if err != nil {
@@ -227,6 +307,39 @@ func (r *Repo) GetPRReviewers(prID string) ([]string, error) {
 	return reviewers, rows.Err()
 }
 
+// GetRecentReviewCounts returns how many of the author's last n pull requests
+// each user reviewed.
+func (r *Repo) GetRecentReviewCounts(authorID string, n int) (map[string]int, error) {
+	query := `
+		select prr.user_id, count(*)
+		from pr_reviewers prr
+		where prr.pull_request_id in (
+			select id from pull_requests
+			where author_id = $1
+			order by created_at desc
+			limit $2
+		)
+		group by prr.user_id;
+	`
+	rows, err := r.db.Query(query, authorID, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var userID string
+		var count int
+		if err := rows.Scan(&userID, &count); err != nil {
+			return nil, err
+		}
+		counts[userID] = count
+	}
+
+	return counts, rows.Err()
+}
+
 func (r *Repo) PRExists(prID string) (bool, error) {
 	var exists bool
 	query := "select exists (select 1 from pull_requests where id = $1);"
@@ -234,49 +347,22 @@ func (r *Repo) PRExists(prID string) (bool, error) {
 	return exists, err
 }
 
-func (r *Repo) UpdatePRStatus(prID string, status string, mergedAt *time.Time) error {
+func (r *Repo) UpdatePRStatus(prID string, status string, mergedAt *time.Time, events ...entities.PREvent) error {
 	query := "update pull_requests set status = $1, merged_at = $2 where id = $3;"
-	result, err := r.db.Exec(query, status, mergedAt, prID)
-	if err != nil {
-		return err
-	}
-	
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rows == 0 {
-		return sql.ErrNoRows
-	}
-	
-	return nil
+	return r.execSCMChange(query, []interface{}{status, mergedAt, prID}, events)
 }
 
-func (r *Repo) RemoveReviewer(prID string, userID string) error {
+func (r *Repo) RemoveReviewer(prID string, userID string, events ...entities.PREvent) error {
 	query := "delete from pr_reviewers where pull_request_id = $1 and user_id = $2;"
-	result, err := r.db.Exec(query, prID, userID)
-	if err != nil {
-		return err
-	}
-	
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rows == 0 {
-		return sql.ErrNoRows
-	}
-	
-	return nil
+	return r.execWithEvents(query, []interface{}{prID, userID}, events)
 }
 
-func (r *Repo) AddReviewer(prID string, userID string) error {
+func (r *Repo) AddReviewer(prID string, userID string, events ...entities.PREvent) error {
 	query := "insert into pr_reviewers (pull_request_id, user_id) values ($1, $2);"
-	_, err := r.db.Exec(query, prID, userID)
-	return err
+	return r.execWithEvents(query, []interface{}{prID, userID}, events)
 }
 
-func (r *Repo) ReplaceReviewer(prID string, oldUserID string, newUserID string) error {
+func (r *Repo) ReplaceReviewer(prID string, oldUserID string, newUserID string, events ...entities.PREvent) error {
 	tx, err := r.db.Begin()
 	if err != nil {
 		return err
@@ -295,18 +381,39 @@ func (r *Repo) ReplaceReviewer(prID string, oldUserID string, newUserID string)
 		return err
 	}
 
+	if err := r.insertEvents(tx, events); err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
 
-func (r *Repo) GetUserReviews(userID string) ([]entities.PullRequestShort, error) {
+// GetUserReviews returns the review queue of a user ordered by assignment
+// time, oldest first, continuing after the (assigned_at, pull_request_id)
+// keyset of the filter.
+func (r *Repo) GetUserReviews(userID string, filter entities.ReviewQueueFilter) ([]entities.PullRequestShort, error) {
 	query := `
-		select pr.id, pr.name, pr.author_id, pr.status
+		select pr.id, pr.name, pr.author_id, pr.status, pr.created_at, prr.assigned_at,
+			coalesce(extract(epoch from localtimestamp - prr.assigned_at)::bigint, 0),
+			coalesce(prr.verdict, '')
 		from pull_requests pr
 		join pr_reviewers prr on pr.id = prr.pull_request_id
-		where prr.user_id = $1
-		order by pr.created_at desc;
-	`
-	rows, err := r.db.Query(query, userID)
+		where prr.user_id = $1`
+	args := []interface{}{userID}
+
+	if filter.Status != "" && filter.Status != entities.StatusAll {
+		args = append(args, filter.Status)
+		query += fmt.Sprintf(" and pr.status = $%d", len(args))
+	}
+	if filter.AfterAssignedAt != nil {
+		args = append(args, *filter.AfterAssignedAt, filter.AfterID)
+		query += fmt.Sprintf(" and (prr.assigned_at, pr.id) > ($%d, $%d)", len(args)-1, len(args))
+	}
+
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" order by prr.assigned_at, pr.id limit $%d;", len(args))
+
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -315,14 +422,19 @@ func (r *Repo) GetUserReviews(userID string) ([]entities.PullRequestShort, error
 	var prs []entities.PullRequestShort
 	for rows.Next() {
 		var pr entities.PullRequestShort
-		if err := rows.Scan(&pr.ID, &pr.Name, &pr.AuthorID, &pr.Status); err != nil {
+		err := rows.Scan(&pr.ID, &pr.Name, &pr.AuthorID, &pr.Status, &pr.CreatedAt, &pr.AssignedAt,
+			&pr.AgeSeconds, &pr.Verdict)
+		if err != nil {
 			return nil, err
 		}
+		pr.HasVerdict = pr.Verdict != ""
 		prs = append(prs, pr)
 	}
 
 	return prs, rows.Err()
 }
 
-
-
+func (r *Repo) SetReviewVerdict(prID, userID, verdict string, events ...entities.PREvent) error {
+	query := "update pr_reviewers set verdict = $1, verdict_at = $2 where pull_request_id = $3 and user_id = $4;"
+	return r.execWithEvents(query, []interface{}{verdict, time.Now().UTC(), prID, userID}, events)
+}
@@ -0,0 +1,97 @@
+package repos
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+// LinkLogin maps a provider login to a user, moving it over if it was linked
+// to someone else.
+func (r *Repo) LinkLogin(l *entities.UserLogin, events ...entities.PREvent) error {
+	query := `insert into user_logins (provider, login, user_id) values ($1, $2, $3)
+				on conflict (provider, login) do update set user_id = excluded.user_id;`
+	return r.execWithEvents(query, []interface{}{l.Provider, l.Login, l.UserID}, events)
+}
+
+func (r *Repo) UnlinkLogin(provider, login string, events ...entities.PREvent) error {
+	query := "delete from user_logins where provider = $1 and login = $2;"
+	return r.execWithEvents(query, []interface{}{provider, login}, events)
+}
+
+func (r *Repo) GetUserLogins(userID string) ([]entities.UserLogin, error) {
+	query := "select provider, login, user_id from user_logins where user_id = $1 order by provider, login;"
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var logins []entities.UserLogin
+	for rows.Next() {
+		var l entities.UserLogin
+		if err := rows.Scan(&l.Provider, &l.Login, &l.UserID); err != nil {
+			return nil, err
+		}
+		logins = append(logins, l)
+	}
+
+	return logins, rows.Err()
+}
+
+func (r *Repo) GetUserIDByLogin(provider, login string) (string, error) {
+	var userID string
+	query := "select user_id from user_logins where provider = $1 and login = $2;"
+	err := r.db.QueryRow(query, provider, login).Scan(&userID)
+	return userID, err
+}
+
+// ErrDuplicateDelivery is returned by the final write of an SCM change through
+// a Repo bound to a webhook delivery that has already been recorded.
+var ErrDuplicateDelivery = errors.New("duplicate delivery")
+
+type inboundDelivery struct {
+	provider string
+	id       string
+}
+
+// WithInboundDelivery returns a Repo bound to a webhook delivery. The writes
+// that finish an SCM change (CreatePullRequest, UpdatePRStatus,
+// ClosePullRequest and ReopenPullRequest) record it in their transaction, so
+// a delivery is remembered exactly when the change it carries commits. A
+// concurrent copy of the delivery waits for the first one and fails with
+// ErrDuplicateDelivery if it committed.
+func (r *Repo) WithInboundDelivery(provider, deliveryID string) *Repo {
+	c := *r
+	c.delivery = &inboundDelivery{provider: provider, id: deliveryID}
+	return &c
+}
+
+// recordDelivery records the bound delivery, if any, in tx.
+func (r *Repo) recordDelivery(tx *sql.Tx) error {
+	if r.delivery == nil {
+		return nil
+	}
+
+	query := "insert into inbound_deliveries (provider, delivery_id) values ($1, $2) on conflict do nothing;"
+	res, err := tx.Exec(query, r.delivery.provider, r.delivery.id)
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrDuplicateDelivery
+	}
+	return nil
+}
+
+func (r *Repo) InboundDeliverySeen(provider, deliveryID string) (bool, error) {
+	var seen bool
+	query := "select exists(select * from inbound_deliveries where provider = $1 and delivery_id = $2);"
+	err := r.db.QueryRow(query, provider, deliveryID).Scan(&seen)
+	return seen, err
+}
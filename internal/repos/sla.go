@@ -0,0 +1,65 @@
+package repos
+
+import (
+	"time"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+func (r *Repo) GetTeamSLA(teamName string) (*entities.TeamSLA, error) {
+	sla := entities.TeamSLA{TeamName: teamName}
+	query := "select review_sla_hours, reassign_after_hours from teams where team_name = $1;"
+	err := r.db.QueryRow(query, teamName).Scan(&sla.ReviewSLAHours, &sla.ReassignAfterHours)
+	if err != nil {
+		return nil, err
+	}
+	return &sla, nil
+}
+
+func (r *Repo) SetTeamSLA(sla *entities.TeamSLA, events ...entities.PREvent) error {
+	query := "update teams set review_sla_hours = $1, reassign_after_hours = $2 where team_name = $3;"
+	return r.execWithEvents(query, []interface{}{sla.ReviewSLAHours, sla.ReassignAfterHours, sla.TeamName}, events)
+}
+
+// GetSLATrackedAssignments returns open assignments without a verdict whose
+// author's team has a review SLA. An empty teamName matches every team.
+func (r *Repo) GetSLATrackedAssignments(teamName string) ([]entities.SLAAssignment, error) {
+	query := `
+		select prr.pull_request_id, pr.name, prr.user_id, t.team_name, prr.assigned_at, prr.sla_breached_at,
+			localtimestamp, t.review_sla_hours, t.reassign_after_hours
+		from pr_reviewers prr
+		join pull_requests pr on pr.id = prr.pull_request_id
+		join users a on a.id = pr.author_id
+		join teams t on t.team_name = a.team_name
+		where pr.status = 'OPEN'
+		and prr.verdict is null
+		and t.review_sla_hours is not null
+		and ($1 = '' or t.team_name = $1)
+		order by prr.assigned_at;
+	`
+	rows, err := r.db.Query(query, teamName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var assignments []entities.SLAAssignment
+	for rows.Next() {
+		var a entities.SLAAssignment
+		err := rows.Scan(&a.PullRequestID, &a.PullRequestName, &a.UserID, &a.TeamName, &a.AssignedAt, &a.BreachedAt,
+			&a.Now, &a.SLA.ReviewSLAHours, &a.SLA.ReassignAfterHours)
+		if err != nil {
+			return nil, err
+		}
+		a.SLA.TeamName = a.TeamName
+		assignments = append(assignments, a)
+	}
+
+	return assignments, rows.Err()
+}
+
+func (r *Repo) MarkSLABreached(prID, userID string, at time.Time, events ...entities.PREvent) error {
+	query := `update pr_reviewers set sla_breached_at = $1
+				where pull_request_id = $2 and user_id = $3 and sla_breached_at is null;`
+	return r.execWithEvents(query, []interface{}{at, prID, userID}, events)
+}
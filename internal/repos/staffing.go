@@ -0,0 +1,36 @@
+package repos
+
+import (
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+// GetUnderstaffedPRs returns OPEN pull requests with fewer than required
+// reviewers, oldest first. An empty teamName matches every team.
+func (r *Repo) GetUnderstaffedPRs(teamName string, required int) ([]entities.UnderstaffedPR, error) {
+	query := `
+		select pr.id, pr.author_id, a.team_name,
+			(select count(*) from pr_reviewers prr where prr.pull_request_id = pr.id) as reviewers
+		from pull_requests pr
+		join users a on a.id = pr.author_id
+		where pr.status = 'OPEN'
+		and ($1 = '' or a.team_name = $1)
+		and (select count(*) from pr_reviewers prr where prr.pull_request_id = pr.id) < $2
+		order by pr.created_at;
+	`
+	rows, err := r.db.Query(query, teamName, required)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prs []entities.UnderstaffedPR
+	for rows.Next() {
+		pr := entities.UnderstaffedPR{Required: required}
+		if err := rows.Scan(&pr.PullRequestID, &pr.AuthorID, &pr.TeamName, &pr.Reviewers); err != nil {
+			return nil, err
+		}
+		prs = append(prs, pr)
+	}
+
+	return prs, rows.Err()
+}
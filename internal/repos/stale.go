@@ -0,0 +1,89 @@
+package repos
+
+import (
+	"time"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+	"github.com/lib/pq"
+)
+
+func (r *Repo) GetStalePolicy(teamName string) (*entities.StalePolicy, error) {
+	p := entities.StalePolicy{TeamName: teamName}
+	query := "select stale_warn_days, stale_close_days, stale_exempt_label from teams where team_name = $1;"
+	err := r.db.QueryRow(query, teamName).Scan(&p.WarnAfterDays, &p.CloseAfterDays, &p.ExemptLabel)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *Repo) SetStalePolicy(p *entities.StalePolicy, events ...entities.PREvent) error {
+	query := "update teams set stale_warn_days = $1, stale_close_days = $2, stale_exempt_label = $3 where team_name = $4;"
+	return r.execWithEvents(query, []interface{}{p.WarnAfterDays, p.CloseAfterDays, p.ExemptLabel, p.TeamName}, events)
+}
+
+func (r *Repo) SetPRLabels(prID string, labels []string, events ...entities.PREvent) error {
+	query := "update pull_requests set labels = $1 where id = $2;"
+	return r.execWithEvents(query, []interface{}{pq.Array(labels), prID}, events)
+}
+
+// GetStaleCandidates returns OPEN pull requests whose author's team has a
+// stale policy, that carry no exemption label and have been idle for at least
+// the shorter of the policy thresholds. Activity is the creation of the PR or
+// any audit event not produced by the server itself. An empty teamName
+// matches every team.
+func (r *Repo) GetStaleCandidates(teamName string) ([]entities.StalePR, error) {
+	query := `
+		select pr.id, pr.name, pr.author_id, t.team_name, a.last_activity, pr.stale_warned_at, localtimestamp,
+			t.stale_warn_days, t.stale_close_days, t.stale_exempt_label
+		from pull_requests pr
+		join users u on u.id = pr.author_id
+		join teams t on t.team_name = u.team_name
+		cross join lateral (
+			select coalesce(greatest(pr.created_at, max(e.created_at)), localtimestamp) as last_activity
+			from pr_events e
+			where e.pull_request_id = pr.id and coalesce(e.actor, '') <> $2
+		) a
+		where pr.status = 'OPEN'
+		and (t.stale_warn_days is not null or t.stale_close_days is not null)
+		and not (t.stale_exempt_label <> '' and t.stale_exempt_label = any(pr.labels))
+		and ($1 = '' or t.team_name = $1)
+		and a.last_activity <= localtimestamp - make_interval(days => least(t.stale_warn_days, t.stale_close_days))
+		order by a.last_activity, pr.id;
+	`
+	rows, err := r.db.Query(query, teamName, entities.ActorSystem)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prs []entities.StalePR
+	for rows.Next() {
+		var p entities.StalePR
+		err := rows.Scan(&p.PullRequestID, &p.PullRequestName, &p.AuthorID, &p.TeamName, &p.LastActivityAt, &p.WarnedAt, &p.Now,
+			&p.Policy.WarnAfterDays, &p.Policy.CloseAfterDays, &p.Policy.ExemptLabel)
+		if err != nil {
+			return nil, err
+		}
+		p.Policy.TeamName = p.TeamName
+		prs = append(prs, p)
+	}
+
+	return prs, rows.Err()
+}
+
+func (r *Repo) MarkStaleWarned(prID string, at time.Time, events ...entities.PREvent) error {
+	query := "update pull_requests set stale_warned_at = $1 where id = $2 and status = 'OPEN';"
+	return r.execWithEvents(query, []interface{}{at, prID}, events)
+}
+
+func (r *Repo) ClosePullRequest(prID string, at time.Time, events ...entities.PREvent) error {
+	query := "update pull_requests set status = 'CLOSED', closed_at = $1 where id = $2 and status = 'OPEN';"
+	return r.execSCMChange(query, []interface{}{at, prID}, events)
+}
+
+func (r *Repo) ReopenPullRequest(prID string, events ...entities.PREvent) error {
+	query := `update pull_requests set status = 'OPEN', closed_at = null, stale_warned_at = null
+				where id = $1 and status = 'CLOSED';`
+	return r.execSCMChange(query, []interface{}{prID}, events)
+}
@@ -0,0 +1,171 @@
+package repos
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+func (r *Repo) ListTeamSettings() ([]entities.TeamSettings, error) {
+	query := `select team_name, review_sla_hours, reassign_after_hours, stale_warn_days, stale_close_days, stale_exempt_label
+				from teams order by team_name;`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var teams []entities.TeamSettings
+	for rows.Next() {
+		var t entities.TeamSettings
+		err := rows.Scan(&t.TeamName, &t.ReviewSLAHours, &t.ReassignAfterHours,
+			&t.StaleWarnAfterDays, &t.StaleCloseAfterDays, &t.StaleExemptLabel)
+		if err != nil {
+			return nil, err
+		}
+		teams = append(teams, t)
+	}
+
+	return teams, rows.Err()
+}
+
+func (r *Repo) ListAllUsers() ([]entities.User, error) {
+	query := `select id, username, team_name, is_active, max_open_reviews, review_weight, email
+				from users order by id;`
+	return r.queryUsers(query)
+}
+
+// ErrOrgChanged is returned by ApplyOrgSync when teams, users or the released
+// reviews changed after the sync was planned.
+var ErrOrgChanged = errors.New("org changed since the sync was planned")
+
+// ApplyOrgSync creates teams, replaces team settings, writes users and drops
+// the released reviews in one transaction. Users are written whole, so they
+// must carry every column that is kept. The sync must have been planned
+// against basisTeams and basisUsers, as listed by ListTeamSettings and
+// ListAllUsers: the rows are locked and compared first, so a concurrent
+// change is never overwritten but fails the sync with ErrOrgChanged.
+func (r *Repo) ApplyOrgSync(basisTeams []entities.TeamSettings, basisUsers []entities.User,
+	teams []string, settings []entities.TeamSettings, users []entities.User,
+	released []entities.ReviewAssignment, events []entities.PREvent) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockOrgBasis(tx, basisTeams, basisUsers); err != nil {
+		return err
+	}
+
+	for _, team := range teams {
+		if _, err := tx.Exec("insert into teams (team_name) values ($1);", team); err != nil {
+			return err
+		}
+	}
+
+	for _, t := range settings {
+		query := `update teams set review_sla_hours = $1, reassign_after_hours = $2,
+					stale_warn_days = $3, stale_close_days = $4, stale_exempt_label = $5
+					where team_name = $6;`
+		_, err := tx.Exec(query, t.ReviewSLAHours, t.ReassignAfterHours,
+			t.StaleWarnAfterDays, t.StaleCloseAfterDays, t.StaleExemptLabel, t.TeamName)
+		if err != nil {
+			return err
+		}
+	}
+
+	now := time.Now().UTC()
+	for _, u := range users {
+		query := `insert into users (id, username, team_name, is_active, max_open_reviews, review_weight, updated_at)
+					values ($1, $2, $3, $4, $5, $6, $7)
+					on conflict (id)
+					do update set
+					username = excluded.username,
+					team_name = excluded.team_name,
+					is_active = excluded.is_active,
+					max_open_reviews = excluded.max_open_reviews,
+					review_weight = excluded.review_weight,
+					updated_at = excluded.updated_at;`
+		_, err := tx.Exec(query, u.ID, u.Username, u.TeamName, u.IsActive, u.MaxOpenReviews, u.ReviewWeight, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, a := range released {
+		query := `delete from pr_reviewers
+					where pull_request_id = $1 and user_id = $2 and verdict is null
+					and pull_request_id in (select id from pull_requests where status = 'OPEN');`
+		res, err := tx.Exec(query, a.PullRequestID, a.UserID)
+		if err != nil {
+			return err
+		}
+		if rows, err := res.RowsAffected(); err != nil {
+			return err
+		} else if rows == 0 {
+			return ErrOrgChanged
+		}
+	}
+
+	if err := r.insertEvents(tx, events); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// lockOrgBasis locks every team and user and fails with ErrOrgChanged unless
+// they still match the state a sync was planned against.
+func lockOrgBasis(tx *sql.Tx, basisTeams []entities.TeamSettings, basisUsers []entities.User) error {
+	query := `select team_name, review_sla_hours, reassign_after_hours, stale_warn_days, stale_close_days, stale_exempt_label
+				from teams order by team_name for update;`
+	rows, err := tx.Query(query)
+	if err != nil {
+		return err
+	}
+	var teams []entities.TeamSettings
+	for rows.Next() {
+		var t entities.TeamSettings
+		err := rows.Scan(&t.TeamName, &t.ReviewSLAHours, &t.ReassignAfterHours,
+			&t.StaleWarnAfterDays, &t.StaleCloseAfterDays, &t.StaleExemptLabel)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		teams = append(teams, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	query = `select id, username, team_name, is_active, max_open_reviews, review_weight, email
+				from users order by id for update;`
+	rows, err = tx.Query(query)
+	if err != nil {
+		return err
+	}
+	var users []entities.User
+	for rows.Next() {
+		var user entities.User
+		err := rows.Scan(&user.ID, &user.Username, &user.TeamName, &user.IsActive, &user.MaxOpenReviews, &user.ReviewWeight, &user.Email)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		users = append(users, user)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if !reflect.DeepEqual(teams, basisTeams) || !reflect.DeepEqual(users, basisUsers) {
+		return ErrOrgChanged
+	}
+	return nil
+}
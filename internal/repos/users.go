@@ -0,0 +1,162 @@
+package repos
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+// ListUsers returns users ordered by id, continuing after filter.AfterID.
+func (r *Repo) ListUsers(filter entities.UserFilter) ([]entities.User, error) {
+	query := `select id, username, team_name, is_active, max_open_reviews, review_weight, email
+				from users where id > $1`
+	args := []interface{}{filter.AfterID}
+
+	if filter.TeamName != "" {
+		args = append(args, filter.TeamName)
+		query += fmt.Sprintf(" and team_name = $%d", len(args))
+	}
+	if filter.IsActive != nil {
+		args = append(args, *filter.IsActive)
+		query += fmt.Sprintf(" and is_active = $%d", len(args))
+	}
+
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" order by id limit $%d;", len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []entities.User
+	for rows.Next() {
+		var user entities.User
+		err := rows.Scan(&user.ID, &user.Username, &user.TeamName, &user.IsActive, &user.MaxOpenReviews, &user.ReviewWeight, &user.Email)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+func (r *Repo) UpdateUsername(id, username string, events ...entities.PREvent) error {
+	query := "update users set username = $1, updated_at = $2 where id = $3;"
+	return r.execWithEvents(query, []interface{}{username, time.Now().UTC(), id}, events)
+}
+
+func (r *Repo) UpdateEmail(id, email string, events ...entities.PREvent) error {
+	query := "update users set email = $1, updated_at = $2 where id = $3;"
+	return r.execWithEvents(query, []interface{}{email, time.Now().UTC(), id}, events)
+}
+
+func (r *Repo) CountAuthoredPRs(userID string) (int, error) {
+	var count int
+	err := r.db.QueryRow("select count(*) from pull_requests where author_id = $1;", userID).Scan(&count)
+	return count, err
+}
+
+// CountReviewHistory counts the pull requests that are no longer open and
+// that the user reviewed or declined.
+func (r *Repo) CountReviewHistory(userID string) (int, error) {
+	query := `select count(*) from pull_requests pr
+				where pr.status <> 'OPEN'
+				and (exists (select 1 from pr_reviewers where pull_request_id = pr.id and user_id = $1)
+					or exists (select 1 from pr_declines where pull_request_id = pr.id and user_id = $1));`
+	var count int
+	err := r.db.QueryRow(query, userID).Scan(&count)
+	return count, err
+}
+
+const dropOpenReviews = `delete from pr_reviewers
+				where user_id = $1
+				and pull_request_id in (select id from pull_requests where status = 'OPEN');`
+
+const dropOpenDeclines = `delete from pr_declines
+				where user_id = $1
+				and pull_request_id in (select id from pull_requests where status = 'OPEN');`
+
+// scrubUserEventDetails removes usernames, emails, chat mentions and SCM
+// logins of a user from the details of their own profile events; the events
+// themselves stay in the audit log.
+const scrubUserEventDetails = `update pr_events set details = case event_type
+					when 'user.upserted' then jsonb_set(details, '{username}', to_jsonb($2::text))
+					when 'user.chat_mention_changed' then details - 'chat_mention'
+					when 'user.login_linked' then details - 'login'
+					when 'user.login_unlinked' then details - 'login'
+					else details - 'from' - 'to'
+				end
+				where user_id = $1 and details is not null
+				and (event_type in ('user.upserted', 'user.chat_mention_changed', 'user.login_linked', 'user.login_unlinked')
+					or (event_type = 'user.updated' and details->>'field' in ('username', 'email')));`
+
+// AnonymizeUser scrubs personal data, including the SCM logins linked to the
+// user and the details of their profile events, deactivates the user and drops
+// their open review assignments. References from pull requests and review
+// history stay valid because the row is kept.
+func (r *Repo) AnonymizeUser(id string, events ...entities.PREvent) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `update users set username = $1, is_active = false, max_open_reviews = null,
+				review_weight = 1.0, email = '', chat_mention = '', updated_at = $2
+				where id = $3;`
+	if _, err := tx.Exec(query, entities.AnonymizedUsername, time.Now().UTC(), id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(scrubUserEventDetails, id, entities.AnonymizedUsername); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("delete from user_logins where user_id = $1;", id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(dropOpenReviews, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("delete from user_availability where user_id = $1;", id); err != nil {
+		return err
+	}
+
+	if err := r.insertEvents(tx, events); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteUser removes the user together with their open review assignments
+// and declines, and scrubs the details of their profile events. Callers must
+// make sure the user authored no pull requests and has no review history.
+func (r *Repo) DeleteUser(id string, events ...entities.PREvent) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(scrubUserEventDetails, id, entities.AnonymizedUsername); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(dropOpenReviews, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(dropOpenDeclines, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("delete from users where id = $1;", id); err != nil {
+		return err
+	}
+
+	if err := r.insertEvents(tx, events); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
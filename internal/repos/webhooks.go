@@ -0,0 +1,218 @@
+package repos
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+	"github.com/lib/pq"
+)
+
+const subscriptionColumns = "id, url, secret, event_types, coalesce(team_name, ''), is_active, created_at"
+
+func scanSubscription(row rowScanner) (*entities.WebhookSubscription, error) {
+	var sub entities.WebhookSubscription
+	err := row.Scan(&sub.ID, &sub.URL, &sub.Secret, pq.Array(&sub.EventTypes), &sub.TeamName, &sub.IsActive, &sub.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if sub.EventTypes == nil {
+		sub.EventTypes = []string{}
+	}
+	return &sub, nil
+}
+
+func (r *Repo) CreateWebhookSubscription(sub *entities.WebhookSubscription) error {
+	query := `insert into webhook_subscriptions (url, secret, event_types, team_name)
+				values ($1, $2, $3, nullif($4, ''))
+				returning id, is_active, created_at;`
+	return r.db.QueryRow(query, sub.URL, sub.Secret, pq.Array(sub.EventTypes), sub.TeamName).
+		Scan(&sub.ID, &sub.IsActive, &sub.CreatedAt)
+}
+
+// UpdateWebhookSecret replaces a subscription secret unless it changed from
+// old in the meantime.
+func (r *Repo) UpdateWebhookSecret(id int64, old, secret string) error {
+	query := "update webhook_subscriptions set secret = $1 where id = $2 and secret = $3;"
+	_, err := r.db.Exec(query, secret, id, old)
+	return err
+}
+
+func (r *Repo) GetWebhookSubscription(id int64) (*entities.WebhookSubscription, error) {
+	query := "select " + subscriptionColumns + " from webhook_subscriptions where id = $1;"
+	return scanSubscription(r.db.QueryRow(query, id))
+}
+
+func (r *Repo) ListWebhookSubscriptions() ([]entities.WebhookSubscription, error) {
+	query := "select " + subscriptionColumns + " from webhook_subscriptions order by id;"
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []entities.WebhookSubscription
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, *sub)
+	}
+
+	return subs, rows.Err()
+}
+
+func (r *Repo) DeleteWebhookSubscription(id int64) error {
+	return r.execWithEvents("delete from webhook_subscriptions where id = $1;", []interface{}{id}, nil)
+}
+
+const deliveryColumns = `d.id, d.subscription_id, d.event_id, e.event_type, d.status, d.attempts, d.next_attempt_at,
+				d.last_status_code, d.last_error, d.delivered_at, d.created_at`
+
+func scanDelivery(row rowScanner) (*entities.WebhookDelivery, error) {
+	var d entities.WebhookDelivery
+	err := row.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Status, &d.Attempts, &d.NextAttemptAt,
+		&d.LastStatusCode, &d.LastError, &d.DeliveredAt, &d.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *Repo) GetWebhookDelivery(id int64) (*entities.WebhookDelivery, error) {
+	query := "select " + deliveryColumns + " from webhook_deliveries d join pr_events e on e.id = d.event_id where d.id = $1;"
+	return scanDelivery(r.db.QueryRow(query, id))
+}
+
+// ListWebhookDeliveries returns deliveries matching the filter in id order,
+// starting after filter.AfterID.
+func (r *Repo) ListWebhookDeliveries(filter entities.WebhookDeliveryFilter) ([]entities.WebhookDelivery, error) {
+	query := "select " + deliveryColumns + " from webhook_deliveries d join pr_events e on e.id = d.event_id where d.id > $1"
+	args := []interface{}{filter.AfterID}
+
+	if filter.SubscriptionID != 0 {
+		args = append(args, filter.SubscriptionID)
+		query += fmt.Sprintf(" and d.subscription_id = $%d", len(args))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		query += fmt.Sprintf(" and d.status = $%d", len(args))
+	}
+
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" order by d.id limit $%d;", len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []entities.WebhookDelivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// ClaimDueDeliveries picks up to limit pending deliveries whose next attempt
+// is due and pushes their next attempt lease into the future, so concurrent
+// dispatchers (or a crashed one) do not send the same delivery twice in a row.
+func (r *Repo) ClaimDueDeliveries(limit int, lease time.Duration) ([]entities.WebhookDelivery, error) {
+	query := `
+		with due as (
+			select id from webhook_deliveries
+			where status = 'PENDING' and next_attempt_at <= localtimestamp
+			order by next_attempt_at, id
+			limit $1
+			for update skip locked
+		)
+		update webhook_deliveries d
+		set next_attempt_at = localtimestamp + make_interval(secs => $2)
+		from due, pr_events e
+		where d.id = due.id and e.id = d.event_id
+		returning ` + deliveryColumns + `;
+	`
+	rows, err := r.db.Query(query, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []entities.WebhookDelivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+func (r *Repo) GetEvent(id int64) (*entities.PREvent, error) {
+	events, err := r.queryEvents("select "+eventColumns+" from pr_events where id = $1;", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &events[0], nil
+}
+
+// GetEvents returns the events with the given ids by id.
+func (r *Repo) GetEvents(ids []int64) (map[int64]*entities.PREvent, error) {
+	events, err := r.queryEvents("select "+eventColumns+" from pr_events where id = any($1);", pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64]*entities.PREvent, len(events))
+	for i := range events {
+		byID[events[i].ID] = &events[i]
+	}
+	return byID, nil
+}
+
+func (r *Repo) MarkDeliveryDelivered(id int64, statusCode int) error {
+	query := `update webhook_deliveries
+				set status = 'DELIVERED', attempts = attempts + 1, last_status_code = $1, last_error = '',
+					delivered_at = localtimestamp
+				where id = $2;`
+	return r.execWithEvents(query, []interface{}{statusCode, id}, nil)
+}
+
+// MarkDeliveryFailed records a failed attempt. A nil retryIn moves the
+// delivery to the dead-letter state.
+func (r *Repo) MarkDeliveryFailed(id int64, statusCode *int, errMsg string, retryIn *time.Duration) error {
+	if retryIn == nil {
+		query := `update webhook_deliveries
+					set status = 'DEAD', attempts = attempts + 1, last_status_code = $1, last_error = $2
+					where id = $3;`
+		return r.execWithEvents(query, []interface{}{statusCode, errMsg, id}, nil)
+	}
+
+	query := `update webhook_deliveries
+				set attempts = attempts + 1, last_status_code = $1, last_error = $2,
+					next_attempt_at = localtimestamp + make_interval(secs => $3)
+				where id = $4;`
+	return r.execWithEvents(query, []interface{}{statusCode, errMsg, retryIn.Seconds(), id}, nil)
+}
+
+// RequeueDelivery schedules a delivery to be sent again right away with a
+// fresh retry budget.
+func (r *Repo) RequeueDelivery(id int64) error {
+	query := `update webhook_deliveries
+				set status = 'PENDING', attempts = 0, next_attempt_at = localtimestamp, delivered_at = null
+				where id = $1 and status = 'DEAD';`
+	return r.execWithEvents(query, []interface{}{id}, nil)
+}
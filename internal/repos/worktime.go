@@ -0,0 +1,155 @@
+package repos
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+	"github.com/lib/pq"
+)
+
+const workScheduleColumns = `id, team_name, coalesce(time_zone, ''),
+	to_char(work_start, 'HH24:MI'), to_char(work_end, 'HH24:MI'), work_days`
+
+func scanWorkSchedule(row rowScanner) (*entities.WorkSchedule, error) {
+	var ws entities.WorkSchedule
+	var days []int64
+	err := row.Scan(&ws.UserID, &ws.TeamName, &ws.TimeZone, &ws.WorkStart, &ws.WorkEnd, pq.Array(&days))
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range days {
+		ws.WorkDays = append(ws.WorkDays, int(d))
+	}
+
+	return &ws, nil
+}
+
+func (r *Repo) GetWorkSchedule(userID string) (*entities.WorkSchedule, error) {
+	query := "select " + workScheduleColumns + " from users where id = $1;"
+	return scanWorkSchedule(r.db.QueryRow(query, userID))
+}
+
+// GetWorkSchedules loads the schedules of the given users keyed by user id.
+func (r *Repo) GetWorkSchedules(userIDs []string) (map[string]entities.WorkSchedule, error) {
+	result := make(map[string]entities.WorkSchedule, len(userIDs))
+	if len(userIDs) == 0 {
+		return result, nil
+	}
+
+	placeholders := make([]string, len(userIDs))
+	args := make([]interface{}, len(userIDs))
+	for i, id := range userIDs {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+
+	query := fmt.Sprintf("select %s from users where id in (%s);", workScheduleColumns, strings.Join(placeholders, ", "))
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		ws, err := scanWorkSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		result[ws.UserID] = *ws
+	}
+
+	return result, rows.Err()
+}
+
+func (r *Repo) SetWorkSchedule(ws *entities.WorkSchedule, events ...entities.PREvent) error {
+	query := `update users set time_zone = nullif($1, ''), work_start = $2, work_end = $3, work_days = $4, updated_at = $5
+				where id = $6;`
+	days := make([]int64, len(ws.WorkDays))
+	for i, d := range ws.WorkDays {
+		days[i] = int64(d)
+	}
+	args := []interface{}{ws.TimeZone, ws.WorkStart, ws.WorkEnd, pq.Array(days), time.Now().UTC(), ws.UserID}
+	return r.execWithEvents(query, args, events)
+}
+
+// GetTeamHolidays returns holidays of the given teams ordered by date.
+func (r *Repo) GetTeamHolidays(teamNames ...string) ([]entities.TeamHoliday, error) {
+	if len(teamNames) == 0 {
+		return nil, nil
+	}
+
+	placeholders := make([]string, len(teamNames))
+	args := make([]interface{}, len(teamNames))
+	for i, name := range teamNames {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = name
+	}
+
+	query := fmt.Sprintf(`select team_name, day, name from team_holidays
+				where team_name in (%s)
+				order by day, team_name;`, strings.Join(placeholders, ", "))
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var holidays []entities.TeamHoliday
+	for rows.Next() {
+		var h entities.TeamHoliday
+		var day time.Time
+		if err := rows.Scan(&h.TeamName, &day, &h.Name); err != nil {
+			return nil, err
+		}
+		h.Date = day.Format(entities.DateLayout)
+		holidays = append(holidays, h)
+	}
+
+	return holidays, rows.Err()
+}
+
+// AddTeamHoliday creates the holiday or renames it when the date is already
+// in the team's calendar.
+func (r *Repo) AddTeamHoliday(h *entities.TeamHoliday, events ...entities.PREvent) error {
+	query := `insert into team_holidays (team_name, day, name) values ($1, $2, $3)
+				on conflict (team_name, day) do update set name = excluded.name;`
+	return r.execWithEvents(query, []interface{}{h.TeamName, h.Date, h.Name}, events)
+}
+
+func (r *Repo) DeleteTeamHoliday(teamName, date string, events ...entities.PREvent) error {
+	query := "delete from team_holidays where team_name = $1 and day = $2;"
+	return r.execWithEvents(query, []interface{}{teamName, date}, events)
+}
+
+// GetOpenReviewTimes returns assignments on open PRs without a verdict,
+// optionally narrowed to one team and/or one reviewer, with the database
+// clock in Now.
+func (r *Repo) GetOpenReviewTimes(teamName, userID string) ([]entities.SLAAssignment, error) {
+	query := `
+		select prr.pull_request_id, pr.name, prr.user_id, u.team_name, prr.assigned_at, localtimestamp
+		from pr_reviewers prr
+		join pull_requests pr on pr.id = prr.pull_request_id
+		join users u on u.id = prr.user_id
+		where pr.status = 'OPEN'
+		and prr.verdict is null
+		and ($1 = '' or u.team_name = $1) and ($2 = '' or u.id = $2);
+	`
+	rows, err := r.db.Query(query, teamName, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var assignments []entities.SLAAssignment
+	for rows.Next() {
+		var a entities.SLAAssignment
+		if err := rows.Scan(&a.PullRequestID, &a.PullRequestName, &a.UserID, &a.TeamName, &a.AssignedAt, &a.Now); err != nil {
+			return nil, err
+		}
+		assignments = append(assignments, a)
+	}
+
+	return assignments, rows.Err()
+}
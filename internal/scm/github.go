@@ -0,0 +1,72 @@
+package scm
+
+import (
+	"encoding/json"
+)
+
+type githubPullRequestEvent struct {
+	Action      string `json:"action"`
+	PullRequest *struct {
+		Number int    `json:"number"`
+		Title  string `json:"title"`
+		Draft  bool   `json:"draft"`
+		Merged bool   `json:"merged"`
+		User   struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	} `json:"pull_request"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+	Sender struct {
+		Login string `json:"login"`
+	} `json:"sender"`
+}
+
+// VerifyGitHubSignature checks the X-Hub-Signature-256 header of a delivery.
+func VerifyGitHubSignature(secret string, body []byte, signature string) bool {
+	return verifyHMAC(secret, body, signature, "sha256=")
+}
+
+// ParseGitHub converts a delivery with the given X-GitHub-Event into a
+// change. Events and actions that do not affect the pull request lifecycle
+// yield a nil change.
+func ParseGitHub(event string, body []byte) (*Change, error) {
+	if event != "pull_request" {
+		return nil, nil
+	}
+
+	var payload githubPullRequestEvent
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, ErrMalformed
+	}
+	pr := payload.PullRequest
+	if pr == nil || pr.Number == 0 || payload.Repository.FullName == "" || pr.User.Login == "" {
+		return nil, ErrMalformed
+	}
+
+	change := &Change{
+		Repo:        payload.Repository.FullName,
+		Number:      pr.Number,
+		Title:       pr.Title,
+		AuthorLogin: pr.User.Login,
+		SenderLogin: payload.Sender.Login,
+		Draft:       pr.Draft,
+	}
+
+	switch payload.Action {
+	case "opened", "ready_for_review":
+		change.Action = ActionOpen
+	case "reopened":
+		change.Action = ActionReopen
+	case "closed":
+		change.Action = ActionClose
+		if pr.Merged {
+			change.Action = ActionMerge
+		}
+	default:
+		return nil, nil
+	}
+
+	return change, nil
+}
@@ -0,0 +1,119 @@
+package scm
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readFixture(t *testing.T, provider, name string) []byte {
+	t.Helper()
+
+	body, err := os.ReadFile(filepath.Join("testdata", provider, name))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	return body
+}
+
+func githubSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestParseGitHub(t *testing.T) {
+	pr := func(action string, draft bool, sender string) *Change {
+		return &Change{
+			Action:      action,
+			Repo:        "acme/backend",
+			Number:      42,
+			Title:       "Add payment retries",
+			AuthorLogin: "Octocat",
+			SenderLogin: sender,
+			Draft:       draft,
+		}
+	}
+
+	tests := []struct {
+		fixture string
+		event   string
+		want    *Change
+	}{
+		{"pull_request_opened.json", "pull_request", pr(ActionOpen, false, "octocat")},
+		{"pull_request_opened_draft.json", "pull_request", pr(ActionOpen, true, "octocat")},
+		{"pull_request_ready_for_review.json", "pull_request", pr(ActionOpen, false, "octocat")},
+		{"pull_request_closed.json", "pull_request", pr(ActionClose, false, "octocat")},
+		{"pull_request_closed_merged.json", "pull_request", pr(ActionMerge, false, "hubot")},
+		{"pull_request_reopened.json", "pull_request", pr(ActionReopen, false, "octocat")},
+		{"ping.json", "ping", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			change, err := ParseGitHub(tt.event, readFixture(t, "github", tt.fixture))
+			if err != nil {
+				t.Fatalf("ParseGitHub: %v", err)
+			}
+			if tt.want == nil {
+				if change != nil {
+					t.Fatalf("ParseGitHub = %+v, want nil", change)
+				}
+				return
+			}
+			if change == nil || *change != *tt.want {
+				t.Fatalf("ParseGitHub = %+v, want %+v", change, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGitHubIgnoresOtherActions(t *testing.T) {
+	body := []byte(`{"action": "labeled", "pull_request": {"number": 42, "user": {"login": "octocat"}},
+		"repository": {"full_name": "acme/backend"}, "sender": {"login": "octocat"}}`)
+	change, err := ParseGitHub("pull_request", body)
+	if err != nil || change != nil {
+		t.Fatalf("ParseGitHub = %+v, %v, want nil, nil", change, err)
+	}
+}
+
+func TestParseGitHubMalformed(t *testing.T) {
+	for _, body := range []string{
+		`not json`,
+		`{"action": "opened", "repository": {"full_name": "acme/backend"}}`,
+		`{"action": "opened", "pull_request": {"number": 42, "user": {"login": "octocat"}}}`,
+	} {
+		if _, err := ParseGitHub("pull_request", []byte(body)); err != ErrMalformed {
+			t.Errorf("ParseGitHub(%s) error = %v, want ErrMalformed", body, err)
+		}
+	}
+}
+
+func TestVerifyGitHubSignature(t *testing.T) {
+	const secret = "s3cret"
+	body := readFixture(t, "github", "pull_request_opened.json")
+
+	tests := []struct {
+		name      string
+		signature string
+		want      bool
+	}{
+		{"valid", githubSignature(secret, body), true},
+		{"wrong secret", githubSignature("other", body), false},
+		{"other body", githubSignature(secret, append(body, ' ')), false},
+		{"missing prefix", githubSignature(secret, body)[len("sha256="):], false},
+		{"not hex", "sha256=zz", false},
+		{"missing", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyGitHubSignature(secret, body, tt.signature); got != tt.want {
+				t.Fatalf("VerifyGitHubSignature = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
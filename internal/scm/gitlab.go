@@ -0,0 +1,86 @@
+package scm
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+)
+
+type gitlabMergeRequestEvent struct {
+	ObjectKind string `json:"object_kind"`
+	User       struct {
+		ID       int    `json:"id"`
+		Username string `json:"username"`
+	} `json:"user"`
+	Project struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+	} `json:"project"`
+	ObjectAttributes *struct {
+		IID            int    `json:"iid"`
+		AuthorID       int    `json:"author_id"`
+		Title          string `json:"title"`
+		Action         string `json:"action"`
+		State          string `json:"state"`
+		Draft          bool   `json:"draft"`
+		WorkInProgress bool   `json:"work_in_progress"`
+	} `json:"object_attributes"`
+}
+
+// VerifyGitLabToken checks the X-Gitlab-Token header of a delivery.
+func VerifyGitLabToken(secret, token string) bool {
+	return subtle.ConstantTimeCompare([]byte(secret), []byte(token)) == 1
+}
+
+// ParseGitLab converts a delivery with the given X-Gitlab-Event into a
+// change. Merge request updates, including draft toggles, are reported as
+// ActionOpen so a merge request that became ready is picked up; other events
+// and actions yield a nil change.
+//
+// GitLab sends only the numeric id of the author, so AuthorLogin is known
+// only when the author triggered the event and is empty otherwise, e.g. when
+// a reviewer marks a draft as ready.
+func ParseGitLab(event string, body []byte) (*Change, error) {
+	if event != "Merge Request Hook" {
+		return nil, nil
+	}
+
+	var payload gitlabMergeRequestEvent
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, ErrMalformed
+	}
+	mr := payload.ObjectAttributes
+	if payload.ObjectKind != "merge_request" || mr == nil || mr.IID == 0 ||
+		payload.Project.PathWithNamespace == "" || payload.User.Username == "" {
+		return nil, ErrMalformed
+	}
+
+	change := &Change{
+		Repo:        payload.Project.PathWithNamespace,
+		Number:      mr.IID,
+		Title:       mr.Title,
+		SenderLogin: payload.User.Username,
+		Draft:       mr.Draft || mr.WorkInProgress,
+	}
+	if mr.AuthorID != 0 && mr.AuthorID == payload.User.ID {
+		change.AuthorLogin = payload.User.Username
+	}
+
+	switch mr.Action {
+	case "open":
+		change.Action = ActionOpen
+	case "update":
+		if mr.State != "opened" {
+			return nil, nil
+		}
+		change.Action = ActionOpen
+	case "reopen":
+		change.Action = ActionReopen
+	case "merge":
+		change.Action = ActionMerge
+	case "close":
+		change.Action = ActionClose
+	default:
+		return nil, nil
+	}
+
+	return change, nil
+}
@@ -0,0 +1,94 @@
+package scm
+
+import "testing"
+
+func TestParseGitLab(t *testing.T) {
+	mr := func(action, title string, draft bool, author, sender string) *Change {
+		return &Change{
+			Action:      action,
+			Repo:        "acme/backend",
+			Number:      7,
+			Title:       title,
+			AuthorLogin: author,
+			SenderLogin: sender,
+			Draft:       draft,
+		}
+	}
+	const title = "Add payment retries"
+
+	tests := []struct {
+		fixture string
+		want    *Change
+	}{
+		{"merge_request_open.json", mr(ActionOpen, title, false, "jsmith", "jsmith")},
+		{"merge_request_open_draft.json", mr(ActionOpen, "Draft: "+title, true, "jsmith", "jsmith")},
+		{"merge_request_update_draft.json", mr(ActionOpen, "Draft: "+title, true, "jsmith", "jsmith")},
+		{"merge_request_update_ready.json", mr(ActionOpen, title, false, "jsmith", "jsmith")},
+		{"merge_request_update_ready_by_reviewer.json", mr(ActionOpen, title, false, "", "alee")},
+		{"merge_request_merge.json", mr(ActionMerge, title, false, "", "maintainer")},
+		{"merge_request_close.json", mr(ActionClose, title, false, "jsmith", "jsmith")},
+		{"merge_request_reopen.json", mr(ActionReopen, title, false, "jsmith", "jsmith")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			change, err := ParseGitLab("Merge Request Hook", readFixture(t, "gitlab", tt.fixture))
+			if err != nil {
+				t.Fatalf("ParseGitLab: %v", err)
+			}
+			if change == nil || *change != *tt.want {
+				t.Fatalf("ParseGitLab = %+v, want %+v", change, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGitLabIgnored(t *testing.T) {
+	open := readFixture(t, "gitlab", "merge_request_open.json")
+	if change, err := ParseGitLab("Push Hook", open); err != nil || change != nil {
+		t.Errorf("ParseGitLab(Push Hook) = %+v, %v, want nil, nil", change, err)
+	}
+
+	for name, body := range map[string]string{
+		"approval":      `{"object_kind": "merge_request", "user": {"id": 1, "username": "jsmith"}, "project": {"path_with_namespace": "acme/backend"}, "object_attributes": {"iid": 7, "author_id": 1, "state": "opened", "action": "approved"}}`,
+		"closed update": `{"object_kind": "merge_request", "user": {"id": 1, "username": "jsmith"}, "project": {"path_with_namespace": "acme/backend"}, "object_attributes": {"iid": 7, "author_id": 1, "state": "closed", "action": "update"}}`,
+	} {
+		if change, err := ParseGitLab("Merge Request Hook", []byte(body)); err != nil || change != nil {
+			t.Errorf("%s: ParseGitLab = %+v, %v, want nil, nil", name, change, err)
+		}
+	}
+}
+
+func TestParseGitLabMalformed(t *testing.T) {
+	for _, body := range []string{
+		`not json`,
+		`{"object_kind": "note", "user": {"id": 1, "username": "jsmith"}, "project": {"path_with_namespace": "acme/backend"}, "object_attributes": {"iid": 7}}`,
+		`{"object_kind": "merge_request", "user": {"id": 1, "username": "jsmith"}, "project": {"path_with_namespace": "acme/backend"}}`,
+		`{"object_kind": "merge_request", "user": {"id": 1}, "project": {"path_with_namespace": "acme/backend"}, "object_attributes": {"iid": 7}}`,
+	} {
+		if _, err := ParseGitLab("Merge Request Hook", []byte(body)); err != ErrMalformed {
+			t.Errorf("ParseGitLab(%s) error = %v, want ErrMalformed", body, err)
+		}
+	}
+}
+
+func TestVerifyGitLabToken(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+		want  bool
+	}{
+		{"valid", "s3cret", true},
+		{"wrong", "s3cre7", false},
+		{"prefix", "s3c", false},
+		{"missing", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyGitLabToken("s3cret", tt.token); got != tt.want {
+				t.Fatalf("VerifyGitLabToken = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+// Package scm turns webhook payloads of code hosting services into pull
+// request changes the service understands.
+package scm
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	ProviderGitHub = "github"
+	ProviderGitLab = "gitlab"
+)
+
+// Providers lists the code hosting services logins can be linked for.
+var Providers = []string{ProviderGitHub, ProviderGitLab}
+
+const (
+	ActionOpen   = "open"
+	ActionMerge  = "merge"
+	ActionClose  = "close"
+	ActionReopen = "reopen"
+)
+
+var ErrMalformed = errors.New("malformed webhook payload")
+
+// Change is a pull request state change reported by a code hosting service.
+// Draft pull requests are reported with Draft set so callers can hold off
+// until they are ready for review. AuthorLogin is empty when the payload does
+// not say who the author is.
+type Change struct {
+	Action      string
+	Repo        string
+	Number      int
+	Title       string
+	AuthorLogin string
+	SenderLogin string
+	Draft       bool
+}
+
+// PullRequestID is the id the pull request is stored under, unique across
+// providers and repositories.
+func (c Change) PullRequestID(provider string) string {
+	return fmt.Sprintf("%s:%s#%d", provider, c.Repo, c.Number)
+}
+
+// verifyHMAC checks a hex HMAC-SHA256 signature of body, optionally prefixed
+// as in "sha256=<hex>".
+func verifyHMAC(secret string, body []byte, signature, prefix string) bool {
+	if !strings.HasPrefix(signature, prefix) {
+		return false
+	}
+	got, err := hex.DecodeString(strings.TrimPrefix(signature, prefix))
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
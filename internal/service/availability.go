@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+const reasonUnavailable = "unavailable"
+
+func validateAvailability(a *entities.Availability) error {
+	start, err := time.Parse(entities.DateLayout, a.StartDate)
+	if err != nil {
+		return errors.New(entities.ErrBadRequest)
+	}
+	end, err := time.Parse(entities.DateLayout, a.EndDate)
+	if err != nil {
+		return errors.New(entities.ErrBadRequest)
+	}
+	if end.Before(start) {
+		return errors.New(entities.ErrBadRequest)
+	}
+	return nil
+}
+
+func (s *Service) AddAvailability(a *entities.Availability) (*entities.Availability, error) {
+	if err := validateAvailability(a); err != nil {
+		return nil, err
+	}
+
+	_, err := s.repo.GetUser(a.UserID)
+	if err == sql.ErrNoRows {
+		return nil, errors.New(entities.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateAvailability(a); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (s *Service) GetUserAvailability(userID string) ([]entities.Availability, error) {
+	_, err := s.repo.GetUser(userID)
+	if err == sql.ErrNoRows {
+		return nil, errors.New(entities.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetUserAvailability(userID)
+}
+
+func (s *Service) UpdateAvailability(a *entities.Availability) (*entities.Availability, error) {
+	if err := validateAvailability(a); err != nil {
+		return nil, err
+	}
+
+	err := s.repo.UpdateAvailability(a)
+	if err == sql.ErrNoRows {
+		return nil, errors.New(entities.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetAvailability(a.ID)
+}
+
+func (s *Service) DeleteAvailability(id int64) error {
+	err := s.repo.DeleteAvailability(id)
+	if err == sql.ErrNoRows {
+		return errors.New(entities.ErrNotFound)
+	}
+	return err
+}
+
+// HandOverUnavailableReviews reassigns open reviews of users who are unavailable
+// today. Assignments without a replacement candidate are left as is.
+func (s *Service) HandOverUnavailableReviews() (int, error) {
+	assignments, err := s.repo.GetUnavailableAssignments()
+	if err != nil {
+		return 0, err
+	}
+
+	handedOver := 0
+	for _, a := range assignments {
+		_, _, err := s.ReassignReviewer(a.PullRequestID, a.UserID, reasonUnavailable, entities.ActorSystem)
+		if err != nil {
+			if err.Error() == entities.ErrNoCandidate {
+				continue
+			}
+			log.Printf("Error handing over review %s from %s: %v", a.PullRequestID, a.UserID, err)
+			continue
+		}
+		handedOver++
+	}
+
+	return handedOver, nil
+}
+
+func (s *Service) RunAvailabilitySync(ctx context.Context, interval time.Duration) {
+	runPeriodically(ctx, interval, "handed over reviews of unavailable users", s.HandOverUnavailableReviews)
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+	"github.com/alexalexbor04/pull_request_service/internal/ical"
+)
+
+// ImportAvailability converts VEVENTs of an iCalendar file into availability
+// windows. Every window is keyed by the event UID and the user, so uploading
+// the same file again leaves the schedules unchanged. Attendee emails are
+// resolved through mapping; an X-USER-ID property overrides attendees.
+func (s *Service) ImportAvailability(data []byte, mapping map[string]string, dryRun bool) (*entities.AvailabilityImportResult, error) {
+	events, err := ical.Parse(data)
+	if err != nil {
+		return nil, errors.New(entities.ErrBadRequest)
+	}
+
+	normalized := make(map[string]string, len(mapping))
+	for email, userID := range mapping {
+		normalized[strings.ToLower(email)] = userID
+	}
+
+	result := &entities.AvailabilityImportResult{DryRun: dryRun}
+	seen := make(map[string]int)
+	knownUsers := make(map[string]bool)
+
+	for _, event := range events {
+		items, err := s.importItems(event, normalized, knownUsers)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			if idx, ok := seen[item.SourceUID]; ok && item.SourceUID != "" {
+				result.Items[idx] = item
+				continue
+			}
+			if item.SourceUID != "" {
+				seen[item.SourceUID] = len(result.Items)
+			}
+			result.Items = append(result.Items, item)
+		}
+	}
+
+	for i := range result.Items {
+		item := &result.Items[i]
+		if item.Action == entities.ImportActionSkip {
+			continue
+		}
+		if err := s.classifyImportItem(item); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, item := range result.Items {
+		switch item.Action {
+		case entities.ImportActionCreate:
+			result.Created++
+		case entities.ImportActionUpdate:
+			result.Updated++
+		case entities.ImportActionDelete:
+			result.Deleted++
+		case entities.ImportActionUnchanged:
+			result.Unchanged++
+		case entities.ImportActionSkip:
+			result.Skipped++
+		}
+	}
+
+	if result.Items == nil {
+		result.Items = []entities.AvailabilityImportItem{}
+	}
+
+	if dryRun {
+		return result, nil
+	}
+
+	if err := s.repo.ApplyAvailabilityImport(result.Items); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *Service) importItems(event ical.Event, mapping map[string]string, knownUsers map[string]bool) ([]entities.AvailabilityImportItem, error) {
+	base := entities.AvailabilityImportItem{
+		StartDate: event.Start.Format(entities.DateLayout),
+		EndDate:   event.End.Format(entities.DateLayout),
+		Reason:    event.Summary,
+	}
+	if event.Status == "CANCELLED" {
+		base.Action = entities.ImportActionDelete
+	}
+
+	if event.UID == "" {
+		base.Action = entities.ImportActionSkip
+		base.Detail = "event has no UID"
+		return []entities.AvailabilityImportItem{base}, nil
+	}
+
+	type target struct{ attendee, userID string }
+	var targets []target
+	if event.UserID != "" {
+		targets = append(targets, target{userID: event.UserID})
+	} else {
+		for _, email := range event.Attendees {
+			targets = append(targets, target{attendee: email, userID: mapping[email]})
+		}
+	}
+
+	if len(targets) == 0 {
+		base.SourceUID = event.UID
+		base.Action = entities.ImportActionSkip
+		base.Detail = "event has no attendee or X-USER-ID"
+		return []entities.AvailabilityImportItem{base}, nil
+	}
+
+	items := make([]entities.AvailabilityImportItem, 0, len(targets))
+	for _, t := range targets {
+		item := base
+		item.Attendee = t.attendee
+		item.UserID = t.userID
+		item.SourceUID = event.UID + "/" + t.userID
+
+		if t.userID == "" {
+			item.SourceUID = event.UID + "/" + t.attendee
+			item.Action = entities.ImportActionSkip
+			item.Detail = "attendee is not mapped to a user"
+		} else {
+			exists, err := s.userExists(t.userID, knownUsers)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				item.Action = entities.ImportActionSkip
+				item.Detail = "user not found"
+			}
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// userExists looks a user up once per import. Only the answer is cached, so
+// a failed lookup fails the import instead of skipping the user.
+func (s *Service) userExists(userID string, cache map[string]bool) (bool, error) {
+	if exists, ok := cache[userID]; ok {
+		return exists, nil
+	}
+	_, err := s.repo.GetUser(userID)
+	if err != nil && err != sql.ErrNoRows {
+		return false, err
+	}
+	cache[userID] = err == nil
+	return err == nil, nil
+}
+
+func (s *Service) classifyImportItem(item *entities.AvailabilityImportItem) error {
+	existing, err := s.repo.GetAvailabilityBySourceUID(item.SourceUID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	if item.Action == entities.ImportActionDelete {
+		if existing == nil {
+			item.Action = entities.ImportActionSkip
+			item.Detail = "cancelled event was never imported"
+		}
+		return nil
+	}
+
+	switch {
+	case existing == nil:
+		item.Action = entities.ImportActionCreate
+	case existing.UserID == item.UserID && existing.StartDate == item.StartDate &&
+		existing.EndDate == item.EndDate && existing.Reason == item.Reason:
+		item.Action = entities.ImportActionUnchanged
+	default:
+		item.Action = entities.ImportActionUpdate
+	}
+
+	return nil
+}
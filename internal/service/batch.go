@@ -0,0 +1,89 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+// The batch lookups below back the GraphQL API, which collects the keys of
+// one level of a query and resolves them together. Keys without a match are
+// left out of the result.
+
+func (s *Service) GetUsersByIDs(ids []string) (map[string]entities.User, error) {
+	users, err := s.repo.GetUsersByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]entities.User, len(users))
+	for _, u := range users {
+		result[u.ID] = u
+	}
+	return result, nil
+}
+
+// GetMembersOfTeams returns team members by team name, ordered by username.
+func (s *Service) GetMembersOfTeams(teamNames []string) (map[string][]entities.User, error) {
+	users, err := s.repo.GetMembersOfTeams(teamNames)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]entities.User)
+	for _, u := range users {
+		result[u.TeamName] = append(result[u.TeamName], u)
+	}
+	return result, nil
+}
+
+func (s *Service) GetPullRequestsByIDs(ids []string) (map[string]entities.PullRequest, error) {
+	prs, err := s.repo.GetPullRequestsByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]entities.PullRequest, len(prs))
+	for _, pr := range prs {
+		result[pr.ID] = pr
+	}
+	return result, nil
+}
+
+// GetAssignmentsForUsers returns review assignments by reviewer, oldest
+// first. Like GetUserReviews, status defaults to OPEN.
+func (s *Service) GetAssignmentsForUsers(userIDs []string, status string) (map[string][]entities.ReviewAssignment, error) {
+	switch status {
+	case "":
+		status = entities.StatusOpen
+	case entities.StatusOpen, entities.StatusMerged, entities.StatusClosed, entities.StatusAll:
+	default:
+		return nil, errors.New(entities.ErrBadRequest)
+	}
+
+	assignments, err := s.repo.GetAssignmentsForUsers(userIDs, status)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]entities.ReviewAssignment)
+	for _, a := range assignments {
+		result[a.UserID] = append(result[a.UserID], a)
+	}
+	return result, nil
+}
+
+// GetAssignmentsForPRs returns review assignments by pull request in
+// assignment order.
+func (s *Service) GetAssignmentsForPRs(prIDs []string) (map[string][]entities.ReviewAssignment, error) {
+	assignments, err := s.repo.GetAssignmentsForPRs(prIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]entities.ReviewAssignment)
+	for _, a := range assignments {
+		result[a.PullRequestID] = append(result[a.PullRequestID], a)
+	}
+	return result, nil
+}
@@ -0,0 +1,340 @@
+package service
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+const (
+	chatActionAssigned   = "assigned"
+	chatActionReassigned = "reassigned"
+)
+
+// ChatMessage is the data chat templates are executed with. Author,
+// Reviewers and OldReviewer hold chat mentions.
+type ChatMessage struct {
+	Action          string
+	TeamName        string
+	PullRequestID   string
+	PullRequestName string
+	Author          string
+	Reviewers       []string
+	OldReviewer     string
+	Reason          string
+}
+
+// ChatDigest is the data of the daily digest of a team's open reviews.
+type ChatDigest struct {
+	TeamName string
+	Date     string
+	Queues   []ChatDigestQueue
+}
+
+type ChatDigestQueue struct {
+	Reviewer string
+	Reviews  []ChatDigestReview
+}
+
+type ChatDigestReview struct {
+	PullRequestID   string
+	PullRequestName string
+	Age             string
+}
+
+const DefaultChatTemplate = `{{if eq .Action "reassigned"}}{{join .Reviewers ", "}}: review of *{{.PullRequestName}}* ({{.PullRequestID}}) by {{.Author}} was reassigned to you from {{.OldReviewer}}` +
+	`{{else}}{{join .Reviewers ", "}}: please review *{{.PullRequestName}}* ({{.PullRequestID}}) by {{.Author}}{{end}}`
+
+const chatDigestTemplate = `Open reviews of team {{.TeamName}} on {{.Date}}:
+{{range .Queues}}
+{{.Reviewer}} ({{len .Reviews}}):
+{{range .Reviews}}• *{{.PullRequestName}}* ({{.PullRequestID}}), waiting {{.Age}}
+{{end}}{{end}}`
+
+var chatFuncs = template.FuncMap{"join": strings.Join}
+
+var chatDigestTmpl = template.Must(template.New("digest").Funcs(chatFuncs).Parse(chatDigestTemplate))
+
+func parseChatTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = DefaultChatTemplate
+	}
+	return template.New("chat").Funcs(chatFuncs).Parse(text)
+}
+
+func renderTemplate(tmpl *template.Template, data interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func (s *Service) GetChatSettings(teamName string) (*entities.ChatSettings, error) {
+	settings, err := s.repo.GetChatSettings(teamName)
+	if err == sql.ErrNoRows {
+		return nil, errors.New(entities.ErrNotFound)
+	}
+	return settings, err
+}
+
+// SetChatSettings configures chat notifications of a team. The template is
+// checked by rendering a sample message.
+func (s *Service) SetChatSettings(settings *entities.ChatSettings, actor string) (*entities.ChatSettings, error) {
+	settings.WebhookURL = strings.TrimSpace(settings.WebhookURL)
+	if settings.WebhookURL != "" {
+		u, err := url.Parse(settings.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, &entities.DomainError{Code: entities.ErrBadRequest, Detail: "webhook_url must be an absolute http(s) URL"}
+		}
+	}
+
+	tmpl, err := parseChatTemplate(settings.Template)
+	if err == nil {
+		_, err = renderTemplate(tmpl, ChatMessage{
+			Action:          chatActionAssigned,
+			TeamName:        settings.TeamName,
+			PullRequestID:   "pr-1",
+			PullRequestName: "Sample",
+			Author:          "@author",
+			Reviewers:       []string{"@reviewer"},
+		})
+	}
+	if err != nil {
+		return nil, &entities.DomainError{Code: entities.ErrBadRequest, Detail: "invalid template: " + err.Error()}
+	}
+
+	digestAt := "off"
+	if settings.DigestAt != nil {
+		clock, err := parseClock(*settings.DigestAt)
+		if err != nil {
+			return nil, &entities.DomainError{Code: entities.ErrBadRequest, Detail: "digest_at must be HH:MM"}
+		}
+		digestAt = time.Time{}.Add(clock).Format(clockLayout)
+		settings.DigestAt = &digestAt
+	}
+
+	err = s.repo.SetChatSettings(settings, entities.PREvent{
+		Type:     entities.EventTeamChatChanged,
+		TeamName: settings.TeamName,
+		Actor:    actor,
+		Details: map[string]string{
+			"enabled":   fmt.Sprint(settings.WebhookURL != ""),
+			"template":  fmt.Sprint(settings.Template != ""),
+			"digest_at": digestAt,
+		},
+	})
+	if err == sql.ErrNoRows {
+		return nil, errors.New(entities.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetChatSettings(settings.TeamName)
+}
+
+// SetChatMention sets how a user is mentioned in chat messages, verbatim:
+// "<@U024BE7LH>" for Slack, "@jsmith" for Mattermost. Empty falls back to
+// the username.
+func (s *Service) SetChatMention(userID, mention, actor string) error {
+	user, err := s.repo.GetUser(userID)
+	if err == sql.ErrNoRows {
+		return errors.New(entities.ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+
+	mention = strings.TrimSpace(mention)
+	return s.repo.SetChatMention(userID, mention, entities.PREvent{
+		Type:     entities.EventUserChatMentionChanged,
+		UserID:   userID,
+		TeamName: user.TeamName,
+		Actor:    actor,
+		Details:  map[string]string{"chat_mention": mention},
+	})
+}
+
+// chatEventKey groups assignment events of one pull request into a single
+// message; each reassignment gets its own.
+func chatEventKey(e *entities.PREvent) string {
+	if e.Type == entities.EventReviewerReassigned {
+		return fmt.Sprintf("%s|%d", e.TeamName, e.ID)
+	}
+	return e.TeamName + "|" + e.PullRequestID
+}
+
+// renderChatEvents builds the message about one group of events. It returns
+// an empty text when there is nothing to say any more.
+func (s *Service) renderChatEvents(settings *entities.ChatSettings, events []*entities.PREvent) (string, error) {
+	first := events[0]
+	pr, err := s.repo.GetPullRequest(first.PullRequestID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	userIDs := []string{pr.AuthorID, first.OldUserID}
+	for _, e := range events {
+		userIDs = append(userIDs, e.NewUserID)
+	}
+	mentions, err := s.repo.GetChatMentions(userIDs)
+	if err != nil {
+		return "", err
+	}
+
+	msg := ChatMessage{
+		Action:          chatActionAssigned,
+		TeamName:        settings.TeamName,
+		PullRequestID:   pr.ID,
+		PullRequestName: pr.Name,
+		Author:          mentions[pr.AuthorID],
+		Reason:          first.Reason,
+	}
+	if first.Type == entities.EventReviewerReassigned {
+		msg.Action = chatActionReassigned
+		msg.OldReviewer = mentions[first.OldUserID]
+	}
+	for _, e := range events {
+		msg.Reviewers = append(msg.Reviewers, mentions[e.NewUserID])
+	}
+
+	tmpl, err := parseChatTemplate(settings.Template)
+	if err != nil {
+		return "", err
+	}
+	return renderTemplate(tmpl, msg)
+}
+
+// renderChatDigest lists the open reviews of every reviewer of the team,
+// oldest first, with their age in the reviewer's working hours. It returns an
+// empty text when nobody has anything to review.
+func (s *Service) renderChatDigest(teamName, date string) (string, error) {
+	assignments, err := s.repo.GetOpenReviewTimes(teamName, "")
+	if err != nil || len(assignments) == 0 {
+		return "", err
+	}
+
+	calendars, err := s.assignmentCalendars(assignments)
+	if err != nil {
+		return "", err
+	}
+
+	sort.Slice(assignments, func(i, j int) bool {
+		if assignments[i].UserID != assignments[j].UserID {
+			return assignments[i].UserID < assignments[j].UserID
+		}
+		return assignments[i].AssignedAt.Before(assignments[j].AssignedAt)
+	})
+
+	userIDs := make([]string, len(assignments))
+	for i, a := range assignments {
+		userIDs[i] = a.UserID
+	}
+	mentions, err := s.repo.GetChatMentions(userIDs)
+	if err != nil {
+		return "", err
+	}
+
+	digest := ChatDigest{TeamName: teamName, Date: date}
+	for i, a := range assignments {
+		if i == 0 || a.UserID != assignments[i-1].UserID {
+			digest.Queues = append(digest.Queues, ChatDigestQueue{Reviewer: mentions[a.UserID]})
+		}
+		queue := &digest.Queues[len(digest.Queues)-1]
+		queue.Reviews = append(queue.Reviews, ChatDigestReview{
+			PullRequestID:   a.PullRequestID,
+			PullRequestName: a.PullRequestName,
+			Age:             formatAge(slaElapsed(a, calendars)),
+		})
+	}
+
+	return renderTemplate(chatDigestTmpl, digest)
+}
+
+// formatAge renders a duration as "2d 3h", "3h 20m" or "15m".
+func formatAge(d time.Duration) string {
+	d = d.Round(time.Minute)
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
+
+// postChat posts text to a Slack/Mattermost-compatible incoming webhook.
+func (s *Service) postChat(webhookURL, text string) error {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.httpClient.Post(webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// sendChat sends one chat message for a group of notifications of a team:
+// either a digest or events sharing a chatEventKey. Messages for teams that
+// turned chat off since are dropped.
+func (s *Service) sendChat(group []entities.Notification, events map[int64]*entities.PREvent) error {
+	settings, err := s.repo.GetChatSettings(group[0].TeamName)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if settings.WebhookURL == "" {
+		return nil
+	}
+
+	var text string
+	if group[0].Kind == entities.NotificationDigest {
+		text, err = s.renderChatDigest(settings.TeamName, *group[0].DigestDate)
+	} else {
+		var groupEvents []*entities.PREvent
+		for _, n := range group {
+			if e := events[*n.EventID]; e != nil {
+				groupEvents = append(groupEvents, e)
+			}
+		}
+		if len(groupEvents) == 0 {
+			return nil
+		}
+		text, err = s.renderChatEvents(settings, groupEvents)
+	}
+	if err != nil || text == "" {
+		return err
+	}
+
+	return s.postChat(settings.WebhookURL, text)
+}
@@ -0,0 +1,323 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+// chatStub stands in for a Slack/Mattermost incoming webhook and records the
+// messages it accepts.
+type chatStub struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	status   int
+	messages []string
+}
+
+func newChatStub(t *testing.T) *chatStub {
+	stub := &chatStub{status: http.StatusOK}
+	stub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Text string `json:"text"`
+		}
+		if r.Header.Get("Content-Type") != "application/json" || json.NewDecoder(r.Body).Decode(&body) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+		if stub.status != http.StatusOK {
+			w.WriteHeader(stub.status)
+			return
+		}
+		stub.messages = append(stub.messages, body.Text)
+	}))
+	t.Cleanup(stub.Close)
+	return stub
+}
+
+func (c *chatStub) setStatus(status int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.status = status
+}
+
+// take removes and returns the recorded messages containing substr.
+func (c *chatStub) take(substr string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var taken, kept []string
+	for _, m := range c.messages {
+		if strings.Contains(m, substr) {
+			taken = append(taken, m)
+		} else {
+			kept = append(kept, m)
+		}
+	}
+	c.messages = kept
+	return taken
+}
+
+func TestPostChat(t *testing.T) {
+	stub := newChatStub(t)
+	s := New(nil)
+
+	if err := s.postChat(stub.URL, "hello *world*"); err != nil {
+		t.Fatalf("postChat: %v", err)
+	}
+	if got := stub.take("hello"); len(got) != 1 || got[0] != "hello *world*" {
+		t.Fatalf("stub received %q, want one %q", got, "hello *world*")
+	}
+
+	stub.setStatus(http.StatusServiceUnavailable)
+	err := s.postChat(stub.URL, "hello again")
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Fatalf("postChat error = %v, want one about status 503", err)
+	}
+}
+
+func TestDefaultChatTemplate(t *testing.T) {
+	tmpl, err := parseChatTemplate("")
+	if err != nil {
+		t.Fatalf("parseChatTemplate: %v", err)
+	}
+
+	tests := []struct {
+		msg  ChatMessage
+		want string
+	}{
+		{
+			ChatMessage{Action: chatActionAssigned, PullRequestID: "pr-1", PullRequestName: "Add search",
+				Author: "alice", Reviewers: []string{"<@U1>", "@bob"}},
+			"<@U1>, @bob: please review *Add search* (pr-1) by alice",
+		},
+		{
+			ChatMessage{Action: chatActionReassigned, PullRequestID: "pr-1", PullRequestName: "Add search",
+				Author: "alice", Reviewers: []string{"@carol"}, OldReviewer: "<@U1>", Reason: "vacation"},
+			"@carol: review of *Add search* (pr-1) by alice was reassigned to you from <@U1>",
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := renderTemplate(tmpl, tt.msg)
+		if err != nil {
+			t.Fatalf("renderTemplate: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("message = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestGroupNotifications(t *testing.T) {
+	eventID := func(id int64) *int64 { return &id }
+	date := "2024-05-02"
+	events := map[int64]*entities.PREvent{
+		1: {ID: 1, Type: entities.EventReviewerAssigned, PullRequestID: "pr-1", TeamName: "backend"},
+		2: {ID: 2, Type: entities.EventReviewerAssigned, PullRequestID: "pr-1", TeamName: "backend"},
+		3: {ID: 3, Type: entities.EventReviewerReassigned, PullRequestID: "pr-1", TeamName: "backend"},
+		4: {ID: 4, Type: entities.EventReviewerReassigned, PullRequestID: "pr-1", TeamName: "backend"},
+		5: {ID: 5, Type: entities.EventReviewerAssigned, PullRequestID: "pr-2", TeamName: "backend"},
+	}
+	notifications := []entities.Notification{
+		{ID: 10, Channel: entities.ChannelChat, EventID: eventID(1)},
+		{ID: 11, Channel: entities.ChannelChat, EventID: eventID(5)},
+		{ID: 12, Channel: entities.ChannelChat, EventID: eventID(2)},
+		{ID: 13, Channel: entities.ChannelChat, EventID: eventID(3)},
+		{ID: 14, Channel: entities.ChannelChat, EventID: eventID(4)},
+		{ID: 15, Channel: entities.ChannelChat, Kind: entities.NotificationDigest, DigestDate: &date},
+		{ID: 16, Channel: entities.ChannelEmail, UserID: "u1", EventID: eventID(1)},
+		{ID: 17, Channel: entities.ChannelEmail, UserID: "u2", EventID: eventID(2)},
+	}
+
+	var got [][]int64
+	for _, group := range groupNotifications(notifications, events) {
+		var ids []int64
+		for _, n := range group {
+			ids = append(ids, n.ID)
+		}
+		got = append(got, ids)
+	}
+
+	want := [][]int64{{10, 12}, {11}, {13}, {14}, {15}, {16}, {17}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("groups = %v, want %v", got, want)
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	tests := map[time.Duration]string{
+		20 * time.Second:                             "0m",
+		15 * time.Minute:                             "15m",
+		3*time.Hour + 20*time.Minute:                 "3h 20m",
+		2*24*time.Hour + 3*time.Hour + 1*time.Minute: "2d 3h",
+	}
+	for d, want := range tests {
+		if got := formatAge(d); got != want {
+			t.Errorf("formatAge(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestChatNotifications(t *testing.T) {
+	s, db, suffix := newTestService(t)
+	stub := newChatStub(t)
+
+	team := "chat-" + suffix
+	author := "author-" + suffix
+	reviewers := []string{"r1-" + suffix, "r2-" + suffix, "r3-" + suffix}
+	createTestTeam(t, s, team, append([]string{author}, reviewers...)...)
+
+	mention := func(id string) string { return "<@" + id + ">" }
+	for _, id := range reviewers {
+		if err := s.SetChatMention(id, mention(id), "test"); err != nil {
+			t.Fatalf("SetChatMention: %v", err)
+		}
+	}
+
+	if _, err := s.SetChatSettings(&entities.ChatSettings{TeamName: team, WebhookURL: stub.URL}, "test"); err != nil {
+		t.Fatalf("SetChatSettings: %v", err)
+	}
+	t.Cleanup(func() {
+		s.SetChatSettings(&entities.ChatSettings{TeamName: team}, "test")
+	})
+
+	pr, err := s.CreatePullRequest("pr-"+suffix, "Add search", author)
+	if err != nil {
+		t.Fatalf("CreatePullRequest: %v", err)
+	}
+	if len(pr.AssignedReviewers) != 2 {
+		t.Fatalf("assigned reviewers = %v, want 2", pr.AssignedReviewers)
+	}
+
+	t.Run("assignment", func(t *testing.T) {
+		sendNotifications(t, s)
+
+		messages := stub.take("(" + pr.ID + ")")
+		if len(messages) != 1 {
+			t.Fatalf("messages about %s = %q, want both assignments in one", pr.ID, messages)
+		}
+
+		tail := fmt.Sprintf(": please review *Add search* (%s) by %s", pr.ID, author)
+		if !strings.HasSuffix(messages[0], tail) {
+			t.Fatalf("message = %q, want it to end with %q", messages[0], tail)
+		}
+		got := strings.Split(strings.TrimSuffix(messages[0], tail), ", ")
+		want := []string{mention(pr.AssignedReviewers[0]), mention(pr.AssignedReviewers[1])}
+		sort.Strings(got)
+		sort.Strings(want)
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("mentioned reviewers = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("reassignment", func(t *testing.T) {
+		old := pr.AssignedReviewers[0]
+		_, replacement, err := s.ReassignReviewer(pr.ID, old, "vacation", "test")
+		if err != nil {
+			t.Fatalf("ReassignReviewer: %v", err)
+		}
+		sendNotifications(t, s)
+
+		want := fmt.Sprintf("%s: review of *Add search* (%s) by %s was reassigned to you from %s",
+			mention(replacement), pr.ID, author, mention(old))
+		if messages := stub.take("(" + pr.ID + ")"); len(messages) != 1 || messages[0] != want {
+			t.Fatalf("messages = %q, want [%q]", messages, want)
+		}
+	})
+
+	t.Run("retry and dead letter", func(t *testing.T) {
+		stub.setStatus(http.StatusServiceUnavailable)
+		failing, err := s.CreatePullRequest("pr2-"+suffix, "Fix login", author)
+		if err != nil {
+			t.Fatalf("CreatePullRequest: %v", err)
+		}
+
+		sendNotifications(t, s)
+		notifications := prNotifications(t, db, entities.ChannelChat, failing.ID)
+		if len(notifications) != 2 {
+			t.Fatalf("notifications = %+v, want 2", notifications)
+		}
+		for _, n := range notifications {
+			if n.status != entities.NotificationPending || n.attempts != 1 || !strings.Contains(n.lastError, "503") {
+				t.Fatalf("notification after a 503 = %+v, want pending with 1 attempt and the error", n)
+			}
+		}
+
+		for attempt := 2; attempt <= notificationMaxAttempts; attempt++ {
+			makeDue(t, db, notifications)
+			sendNotifications(t, s)
+		}
+		notifications = prNotifications(t, db, entities.ChannelChat, failing.ID)
+		for _, n := range notifications {
+			if n.status != entities.NotificationDead || n.attempts != notificationMaxAttempts {
+				t.Fatalf("notification = %+v, want dead after %d attempts", n, notificationMaxAttempts)
+			}
+		}
+		if messages := stub.take("(" + failing.ID + ")"); len(messages) != 0 {
+			t.Fatalf("stub accepted %q while failing", messages)
+		}
+
+		stub.setStatus(http.StatusOK)
+		for _, n := range notifications {
+			if _, err := s.RetryNotification(n.id); err != nil {
+				t.Fatalf("RetryNotification: %v", err)
+			}
+		}
+		sendNotifications(t, s)
+
+		if messages := stub.take("(" + failing.ID + ")"); len(messages) != 1 {
+			t.Fatalf("messages after retry = %q, want one", messages)
+		}
+		for _, n := range prNotifications(t, db, entities.ChannelChat, failing.ID) {
+			if n.status != entities.NotificationSent {
+				t.Fatalf("notification after retry = %+v, want sent", n)
+			}
+		}
+	})
+
+	t.Run("daily digest", func(t *testing.T) {
+		digestAt := "00:00"
+		settings := &entities.ChatSettings{TeamName: team, WebhookURL: stub.URL, DigestAt: &digestAt}
+		if _, err := s.SetChatSettings(settings, "test"); err != nil {
+			t.Fatalf("SetChatSettings: %v", err)
+		}
+
+		sendNotifications(t, s)
+		heading := "Open reviews of team " + team + " on "
+		digests := stub.take(heading)
+		if len(digests) != 1 {
+			t.Fatalf("digests = %q, want one", digests)
+		}
+
+		current, err := s.GetPullRequest(pr.ID)
+		if err != nil {
+			t.Fatalf("GetPullRequest: %v", err)
+		}
+		for _, id := range current.AssignedReviewers {
+			if !strings.Contains(digests[0], "\n"+mention(id)+" (") {
+				t.Errorf("digest %q has no queue of %s", digests[0], mention(id))
+			}
+		}
+		if line := "• *Add search* (" + pr.ID + "), waiting "; !strings.Contains(digests[0], line) {
+			t.Errorf("digest %q does not list %q", digests[0], line)
+		}
+
+		sendNotifications(t, s)
+		if digests := stub.take(heading); len(digests) != 0 {
+			t.Fatalf("second digest on the same day: %q", digests)
+		}
+	})
+}
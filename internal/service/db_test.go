@@ -0,0 +1,89 @@
+package service
+
+import (
+	"database/sql"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+	"github.com/alexalexbor04/pull_request_service/internal/repos"
+	"github.com/alexalexbor04/pull_request_service/internal/testdb"
+)
+
+// newTestService returns a service over the test database, skipping the test
+// without one, and a suffix for ids of the test's own.
+func newTestService(t *testing.T) (*Service, *sql.DB, string) {
+	t.Helper()
+
+	db := testdb.Open(t)
+	return New(repos.New(db)), db, strconv.FormatInt(time.Now().UnixNano(), 36)
+}
+
+// createTestTeam creates a team of active users whose usernames are their
+// ids.
+func createTestTeam(t *testing.T, s *Service, teamName string, userIDs ...string) {
+	t.Helper()
+
+	team := &entities.Team{TeamName: teamName}
+	for _, id := range userIDs {
+		team.Members = append(team.Members, entities.TeamMember{UserID: id, Username: id, IsActive: true})
+	}
+	if err := s.CreateTeam(team); err != nil {
+		t.Fatalf("CreateTeam: %v", err)
+	}
+}
+
+type testNotification struct {
+	id        int64
+	status    string
+	attempts  int
+	lastError string
+}
+
+// prNotifications returns the notifications queued for events of a pull
+// request on a channel.
+func prNotifications(t *testing.T, db *sql.DB, channel, prID string) []testNotification {
+	t.Helper()
+
+	rows, err := db.Query(`select n.id, n.status, n.attempts, n.last_error from notifications n
+				join pr_events e on e.id = n.event_id
+				where n.channel = $1 and e.pull_request_id = $2 order by n.id;`, channel, prID)
+	if err != nil {
+		t.Fatalf("query notifications: %v", err)
+	}
+	defer rows.Close()
+
+	var notifications []testNotification
+	for rows.Next() {
+		var n testNotification
+		if err := rows.Scan(&n.id, &n.status, &n.attempts, &n.lastError); err != nil {
+			t.Fatalf("scan notification: %v", err)
+		}
+		notifications = append(notifications, n)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("query notifications: %v", err)
+	}
+	return notifications
+}
+
+// makeDue lets the next SendNotifications pick the notifications up again
+// instead of waiting out their backoff.
+func makeDue(t *testing.T, db *sql.DB, notifications []testNotification) {
+	t.Helper()
+
+	for _, n := range notifications {
+		if _, err := db.Exec("update notifications set next_attempt_at = localtimestamp - interval '1 second' where id = $1;", n.id); err != nil {
+			t.Fatalf("make notification due: %v", err)
+		}
+	}
+}
+
+func sendNotifications(t *testing.T, s *Service) {
+	t.Helper()
+
+	if _, err := s.SendNotifications(); err != nil {
+		t.Fatalf("SendNotifications: %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+func validDeclineReason(reason string) bool {
+	for _, r := range entities.DeclineReasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// DeclineReview lets a reviewer step down from a PR. A replacement is picked
+// like in ReassignReviewer, excluding everyone who declined this PR before.
+// When nobody can take over, the reviewer is still removed and the returned
+// replacement id is empty.
+func (s *Service) DeclineReview(prID, userID, reason, comment string) (*entities.PullRequest, string, error) {
+	if !validDeclineReason(reason) {
+		return nil, "", errors.New(entities.ErrBadRequest)
+	}
+
+	pr, user, err := s.loadAssignment(prID, userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	newReviewerID := ""
+	newReviewer, err := s.pickReplacement(pr, user)
+	if err != nil && err.Error() != entities.ErrNoCandidate {
+		return nil, "", err
+	}
+	if newReviewer != nil {
+		newReviewerID = newReviewer.ID
+	}
+
+	if err := s.repo.DeclineReviewer(prID, userID, reason, comment, newReviewerID, entities.PREvent{
+		Type:          entities.EventReviewerDeclined,
+		PullRequestID: prID,
+		OldUserID:     userID,
+		NewUserID:     newReviewerID,
+		TeamName:      user.TeamName,
+		Reason:        reason,
+		Actor:         userID,
+	}); err != nil {
+		return nil, "", err
+	}
+
+	updatedPR, err := s.repo.GetPullRequest(prID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return updatedPR, newReviewerID, nil
+}
+
+func (s *Service) GetReviewerStats(teamName, userID string) ([]entities.ReviewerStats, error) {
+	if teamName != "" {
+		exists, err := s.repo.TeamExists(teamName)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, errors.New(entities.ErrNotFound)
+		}
+	}
+
+	stats, err := s.repo.GetReviewerStats(teamName, userID)
+	if err != nil {
+		return nil, err
+	}
+	if userID != "" && len(stats) == 0 {
+		return nil, errors.New(entities.ErrNotFound)
+	}
+
+	if err := s.addReviewAges(stats, teamName, userID); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// addReviewAges fills the age of open reviews of every reviewer, counted in
+// business time for reviewers with a work schedule.
+func (s *Service) addReviewAges(stats []entities.ReviewerStats, teamName, userID string) error {
+	userIDs := make([]string, len(stats))
+	for i, st := range stats {
+		userIDs[i] = st.UserID
+	}
+	schedules, err := s.repo.GetWorkSchedules(userIDs)
+	if err != nil {
+		return err
+	}
+	calendars, err := s.workCalendars(userIDs)
+	if err != nil {
+		return err
+	}
+	assignments, err := s.repo.GetOpenReviewTimes(teamName, userID)
+	if err != nil {
+		return err
+	}
+
+	ages := make(map[string][]time.Duration)
+	for _, a := range assignments {
+		ages[a.UserID] = append(ages[a.UserID], calendars[a.UserID].elapsed(a.AssignedAt, a.Now))
+	}
+
+	for i := range stats {
+		st := &stats[i]
+		st.AgeClock = entities.ClockWall
+		if calendars[st.UserID] != nil {
+			st.AgeClock = entities.ClockBusiness
+			ws := schedules[st.UserID]
+			st.WorkSchedule = &ws
+		}
+
+		var total time.Duration
+		for _, age := range ages[st.UserID] {
+			total += age
+			if age > time.Duration(st.OldestOpenReviewAgeSeconds)*time.Second {
+				st.OldestOpenReviewAgeSeconds = int64(age / time.Second)
+			}
+		}
+		if n := len(ages[st.UserID]); n > 0 {
+			st.AvgOpenReviewAgeSeconds = int64(total / time.Duration(n) / time.Second)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,353 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	htmltemplate "html/template"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+	"github.com/alexalexbor04/pull_request_service/internal/mail"
+)
+
+// EmailMessage is the data email templates are executed with. Author and
+// OldReviewer hold usernames.
+type EmailMessage struct {
+	Topic           string
+	Recipient       string
+	PullRequestID   string
+	PullRequestName string
+	Author          string
+	OldReviewer     string
+	Reason          string
+	SLAHours        string
+	ElapsedHours    string
+	Date            string
+	Reviews         []ChatDigestReview
+}
+
+type emailTemplate struct {
+	subject *template.Template
+	text    *template.Template
+	html    *htmltemplate.Template
+}
+
+func newEmailTemplate(subject, text, html string) emailTemplate {
+	return emailTemplate{
+		subject: template.Must(template.New("subject").Parse(subject)),
+		text:    template.Must(template.New("text").Parse(text)),
+		html:    htmltemplate.Must(htmltemplate.New("html").Parse(html)),
+	}
+}
+
+const emailHTMLHeader = `<!DOCTYPE html><html><body style="font-family: sans-serif">`
+const emailHTMLFooter = `</body></html>`
+
+var emailTemplates = map[string]emailTemplate{
+	entities.EmailAssignment: newEmailTemplate(
+		`Review requested: {{.PullRequestName}}`,
+		`Hi {{.Recipient}},
+
+{{.Author}} asked you to review "{{.PullRequestName}}" ({{.PullRequestID}}).
+`,
+		emailHTMLHeader+`<p>Hi {{.Recipient}},</p>
+<p>{{.Author}} asked you to review <b>{{.PullRequestName}}</b> ({{.PullRequestID}}).</p>`+emailHTMLFooter),
+
+	entities.EmailReassignment: newEmailTemplate(
+		`Review reassigned to you: {{.PullRequestName}}`,
+		`Hi {{.Recipient}},
+
+The review of "{{.PullRequestName}}" ({{.PullRequestID}}) by {{.Author}} was reassigned to you from {{.OldReviewer}}.
+{{if .Reason}}Reason: {{.Reason}}
+{{end}}`,
+		emailHTMLHeader+`<p>Hi {{.Recipient}},</p>
+<p>The review of <b>{{.PullRequestName}}</b> ({{.PullRequestID}}) by {{.Author}} was reassigned to you from {{.OldReviewer}}.</p>
+{{if .Reason}}<p>Reason: {{.Reason}}</p>{{end}}`+emailHTMLFooter),
+
+	entities.EmailSLABreach: newEmailTemplate(
+		`Review overdue: {{.PullRequestName}}`,
+		`Hi {{.Recipient}},
+
+Your review of "{{.PullRequestName}}" ({{.PullRequestID}}) by {{.Author}} has been waiting {{.ElapsedHours}} working hours, over the team's {{.SLAHours}}-hour review SLA.
+`,
+		emailHTMLHeader+`<p>Hi {{.Recipient}},</p>
+<p>Your review of <b>{{.PullRequestName}}</b> ({{.PullRequestID}}) by {{.Author}} has been waiting {{.ElapsedHours}} working hours, over the team's {{.SLAHours}}-hour review SLA.</p>`+emailHTMLFooter),
+
+	entities.EmailDigest: newEmailTemplate(
+		`Your open reviews on {{.Date}}`,
+		`Hi {{.Recipient}},
+
+You have {{len .Reviews}} open review(s):
+{{range .Reviews}}- "{{.PullRequestName}}" ({{.PullRequestID}}), waiting {{.Age}}
+{{end}}`,
+		emailHTMLHeader+`<p>Hi {{.Recipient}},</p>
+<p>You have {{len .Reviews}} open review(s):</p>
+<ul>{{range .Reviews}}<li><b>{{.PullRequestName}}</b> ({{.PullRequestID}}), waiting {{.Age}}</li>{{end}}</ul>`+emailHTMLFooter),
+}
+
+// SetMailer enables email notifications. Without a mailer no emails are
+// queued.
+func (s *Service) SetMailer(m *mail.Mailer) {
+	s.mailer = m
+	s.repo.SetEmailEnabled(m != nil)
+}
+
+func (s *Service) GetEmailPreferences(userID string) (*entities.EmailPreferences, error) {
+	prefs, err := s.repo.GetEmailPreferences(userID)
+	if err == sql.ErrNoRows {
+		return nil, errors.New(entities.ErrNotFound)
+	}
+	return prefs, err
+}
+
+// SetEmailPreferences replaces the list of topics a user opted out of.
+func (s *Service) SetEmailPreferences(prefs *entities.EmailPreferences, actor string) (*entities.EmailPreferences, error) {
+	seen := make(map[string]bool)
+	optOut := []string{}
+	for _, topic := range prefs.OptOut {
+		if !isEmailTopic(topic) {
+			return nil, &entities.DomainError{
+				Code:   entities.ErrBadRequest,
+				Detail: "unknown email topic " + topic + "; expected one of " + strings.Join(entities.EmailTopics, ", "),
+			}
+		}
+		if !seen[topic] {
+			seen[topic] = true
+			optOut = append(optOut, topic)
+		}
+	}
+	sort.Strings(optOut)
+	prefs.OptOut = optOut
+
+	user, err := s.repo.GetUser(prefs.UserID)
+	if err == sql.ErrNoRows {
+		return nil, errors.New(entities.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetEmailPreferences(prefs, entities.PREvent{
+		Type:     entities.EventUserEmailPreferencesChanged,
+		UserID:   prefs.UserID,
+		TeamName: user.TeamName,
+		Actor:    actor,
+		Details:  map[string]string{"opt_out": strings.Join(optOut, ",")},
+	}); err != nil {
+		return nil, err
+	}
+
+	return prefs, nil
+}
+
+func isEmailTopic(topic string) bool {
+	for _, t := range entities.EmailTopics {
+		if t == topic {
+			return true
+		}
+	}
+	return false
+}
+
+// EnqueueEmailDigests queues today's digest for every user with open reviews
+// once their working day has started in their time zone, UTC if they have
+// none. Days off and team holidays get no digest.
+func (s *Service) EnqueueEmailDigests(now time.Time) (int, error) {
+	schedules, err := s.repo.GetEmailDigestCandidates()
+	if err != nil || len(schedules) == 0 {
+		return 0, err
+	}
+
+	teamSeen := make(map[string]bool)
+	var teams []string
+	for _, ws := range schedules {
+		if !teamSeen[ws.TeamName] {
+			teamSeen[ws.TeamName] = true
+			teams = append(teams, ws.TeamName)
+		}
+	}
+	holidays, err := s.repo.GetTeamHolidays(teams...)
+	if err != nil {
+		return 0, err
+	}
+
+	queued := 0
+	for _, ws := range schedules {
+		if ws.TimeZone == "" {
+			ws.TimeZone = "UTC"
+		}
+		calendar, err := newWorkCalendar(ws, holidays)
+		if err != nil {
+			continue
+		}
+
+		local := now.In(calendar.loc)
+		day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, calendar.loc)
+		if !calendar.isWorkDay(day) || local.Before(atClock(day, calendar.start)) {
+			continue
+		}
+
+		ok, err := s.repo.EnqueueEmailDigest(ws.UserID, ws.TeamName, day.Format(entities.DateLayout))
+		if err != nil {
+			return queued, err
+		}
+		if ok {
+			queued++
+		}
+	}
+
+	return queued, nil
+}
+
+// renderEmailEvent fills msg from the event a notification was queued for.
+// It reports false when the email is no longer relevant.
+func (s *Service) renderEmailEvent(msg *EmailMessage, e *entities.PREvent) (bool, error) {
+	pr, err := s.repo.GetPullRequest(e.PullRequestID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if pr.Status != entities.StatusOpen {
+		return false, nil
+	}
+
+	usernames, err := s.usernames(pr.AuthorID, e.OldUserID)
+	if err != nil {
+		return false, err
+	}
+
+	msg.PullRequestID = pr.ID
+	msg.PullRequestName = pr.Name
+	msg.Author = usernames[pr.AuthorID]
+	msg.OldReviewer = usernames[e.OldUserID]
+	msg.Reason = e.Reason
+	msg.SLAHours = e.Details["sla_hours"]
+	msg.ElapsedHours = e.Details["elapsed_hours"]
+	return true, nil
+}
+
+func (s *Service) usernames(userIDs ...string) (map[string]string, error) {
+	usernames := make(map[string]string)
+	for _, id := range userIDs {
+		if id == "" || usernames[id] != "" {
+			continue
+		}
+		user, err := s.repo.GetUser(id)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		usernames[id] = user.Username
+	}
+	return usernames, nil
+}
+
+// renderEmailDigest lists the open reviews of a user, oldest first, with
+// their age in the user's working hours. It reports false when there are none.
+func (s *Service) renderEmailDigest(msg *EmailMessage, user *entities.User, date string) (bool, error) {
+	assignments, err := s.repo.GetOpenReviewTimes(user.TeamName, user.ID)
+	if err != nil || len(assignments) == 0 {
+		return false, err
+	}
+
+	calendars, err := s.assignmentCalendars(assignments)
+	if err != nil {
+		return false, err
+	}
+
+	sort.Slice(assignments, func(i, j int) bool {
+		return assignments[i].AssignedAt.Before(assignments[j].AssignedAt)
+	})
+
+	msg.Date = date
+	for _, a := range assignments {
+		msg.Reviews = append(msg.Reviews, ChatDigestReview{
+			PullRequestID:   a.PullRequestID,
+			PullRequestName: a.PullRequestName,
+			Age:             formatAge(slaElapsed(a, calendars)),
+		})
+	}
+	return true, nil
+}
+
+// sendEmail sends one email for a group of notifications of a user: either a
+// digest or events sharing a chatEventKey, which only ever hold one event
+// worth mailing. Emails to users who removed their address or opted out of
+// the topic since they were queued are dropped.
+func (s *Service) sendEmail(group []entities.Notification, events map[int64]*entities.PREvent) error {
+	n := group[0]
+	user, err := s.repo.GetUser(n.UserID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.Email == "" {
+		return nil
+	}
+
+	msg := EmailMessage{Recipient: user.Username}
+	relevant := false
+	if n.Kind == entities.NotificationDigest {
+		msg.Topic = entities.EmailDigest
+		relevant, err = s.renderEmailDigest(&msg, user, *n.DigestDate)
+	} else if e := events[*n.EventID]; e != nil {
+		msg.Topic = emailEventTopic(e.Type)
+		relevant, err = s.renderEmailEvent(&msg, e)
+	}
+	if err != nil || !relevant || msg.Topic == "" {
+		return err
+	}
+
+	prefs, err := s.repo.GetEmailPreferences(user.ID)
+	if err != nil {
+		return err
+	}
+	for _, topic := range prefs.OptOut {
+		if topic == msg.Topic {
+			return nil
+		}
+	}
+
+	tmpl := emailTemplates[msg.Topic]
+	subject, err := renderTemplate(tmpl.subject, msg)
+	if err != nil {
+		return err
+	}
+	text, err := renderTemplate(tmpl.text, msg)
+	if err != nil {
+		return err
+	}
+	var html strings.Builder
+	if err := tmpl.html.Execute(&html, msg); err != nil {
+		return err
+	}
+
+	return s.mailer.Send(mail.Message{
+		To:      user.Email,
+		ToName:  user.Username,
+		Subject: subject,
+		Text:    text,
+		HTML:    html.String(),
+	})
+}
+
+func emailEventTopic(eventType string) string {
+	switch eventType {
+	case entities.EventReviewerAssigned, entities.EventReviewerAdded:
+		return entities.EmailAssignment
+	case entities.EventReviewerReassigned:
+		return entities.EmailReassignment
+	case entities.EventSLABreached:
+		return entities.EmailSLABreach
+	}
+	return ""
+}
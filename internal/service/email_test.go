@@ -0,0 +1,161 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+	"github.com/alexalexbor04/pull_request_service/internal/mail"
+	"github.com/alexalexbor04/pull_request_service/internal/mail/mailtest"
+)
+
+// takeEmails returns the messages the server accepted since the previous call
+// whose subject contains substr.
+func takeEmails(t *testing.T, srv *mailtest.Server, seen *int, substr string) []*mailtest.Parsed {
+	t.Helper()
+
+	messages := srv.Messages()
+	var taken []*mailtest.Parsed
+	for _, m := range messages[*seen:] {
+		parsed, err := m.Parse()
+		if err != nil {
+			t.Fatalf("parse email: %v", err)
+		}
+		if strings.Contains(parsed.Subject, substr) {
+			taken = append(taken, parsed)
+		}
+	}
+	*seen = len(messages)
+	return taken
+}
+
+func TestEmailNotifications(t *testing.T) {
+	s, db, suffix := newTestService(t)
+	srv := mailtest.NewServer()
+	t.Cleanup(srv.Close)
+	s.SetMailer(&mail.Mailer{Addr: srv.Addr, From: "Review Bot <bot@example.com>"})
+	seen := 0
+
+	team := "email-" + suffix
+	author := "author-" + suffix
+	reviewer := "r1-" + suffix
+	optedOut := "r2-" + suffix
+	createTestTeam(t, s, team, author, reviewer, optedOut)
+
+	for _, id := range []string{author, reviewer, optedOut} {
+		email := id + "@example.com"
+		if _, err := s.UpdateUser(id, entities.UserUpdate{Email: &email}, "test"); err != nil {
+			t.Fatalf("UpdateUser: %v", err)
+		}
+	}
+	prefs := &entities.EmailPreferences{UserID: optedOut, OptOut: []string{entities.EmailAssignment}}
+	if _, err := s.SetEmailPreferences(prefs, "test"); err != nil {
+		t.Fatalf("SetEmailPreferences: %v", err)
+	}
+
+	createPR := func(t *testing.T, id, name string) *entities.PullRequest {
+		t.Helper()
+
+		pr, err := s.CreatePullRequest(id, name, author)
+		if err != nil {
+			t.Fatalf("CreatePullRequest: %v", err)
+		}
+		if len(pr.AssignedReviewers) != 2 {
+			t.Fatalf("assigned reviewers = %v, want both team members", pr.AssignedReviewers)
+		}
+		return pr
+	}
+
+	t.Run("assignment", func(t *testing.T) {
+		name := "Add search " + suffix
+		pr := createPR(t, "pr-"+suffix, name)
+		if got := prNotifications(t, db, entities.ChannelEmail, pr.ID); len(got) != 1 {
+			t.Fatalf("queued emails = %+v, want one, none for the opted-out reviewer", got)
+		}
+		sendNotifications(t, s)
+
+		emails := takeEmails(t, srv, &seen, name)
+		if len(emails) != 1 {
+			t.Fatalf("emails about %s = %d, want 1", pr.ID, len(emails))
+		}
+		email := emails[0]
+		if to := email.Header.Get("To"); to != fmt.Sprintf(`"%s" <%s@example.com>`, reviewer, reviewer) {
+			t.Errorf("To = %q, want %s", to, reviewer)
+		}
+		if email.Subject != "Review requested: "+name {
+			t.Errorf("Subject = %q", email.Subject)
+		}
+		text := fmt.Sprintf("Hi %s,\n\n%s asked you to review \"%s\" (%s).\n", reviewer, author, name, pr.ID)
+		if email.Text != text {
+			t.Errorf("text part = %q, want %q", email.Text, text)
+		}
+		html := fmt.Sprintf("<p>%s asked you to review <b>%s</b> (%s).</p>", author, name, pr.ID)
+		if !strings.HasPrefix(email.HTML, "<!DOCTYPE html>") || !strings.Contains(email.HTML, html) {
+			t.Errorf("HTML part = %q, want a document with %q", email.HTML, html)
+		}
+	})
+
+	t.Run("opt-out after queueing", func(t *testing.T) {
+		name := "Update docs " + suffix
+		pr := createPR(t, "pr2-"+suffix, name)
+
+		prefs := &entities.EmailPreferences{UserID: reviewer, OptOut: []string{entities.EmailAssignment}}
+		if _, err := s.SetEmailPreferences(prefs, "test"); err != nil {
+			t.Fatalf("SetEmailPreferences: %v", err)
+		}
+		t.Cleanup(func() {
+			s.SetEmailPreferences(&entities.EmailPreferences{UserID: reviewer}, "test")
+		})
+		sendNotifications(t, s)
+
+		if emails := takeEmails(t, srv, &seen, name); len(emails) != 0 {
+			t.Fatalf("sent %d emails to a reviewer who opted out", len(emails))
+		}
+		for _, n := range prNotifications(t, db, entities.ChannelEmail, pr.ID) {
+			if n.status != entities.NotificationSent {
+				t.Fatalf("dropped notification = %+v, want it settled", n)
+			}
+		}
+	})
+
+	t.Run("retry and dead letter", func(t *testing.T) {
+		srv.Reject("451 4.3.0 Try again later")
+		name := "Fix login " + suffix
+		pr := createPR(t, "pr3-"+suffix, name)
+
+		sendNotifications(t, s)
+		notifications := prNotifications(t, db, entities.ChannelEmail, pr.ID)
+		if len(notifications) != 1 {
+			t.Fatalf("notifications = %+v, want 1", notifications)
+		}
+		if n := notifications[0]; n.status != entities.NotificationPending || n.attempts != 1 || !strings.Contains(n.lastError, "451") {
+			t.Fatalf("notification after a 451 = %+v, want pending with 1 attempt and the error", n)
+		}
+
+		for attempt := 2; attempt <= notificationMaxAttempts; attempt++ {
+			makeDue(t, db, notifications)
+			sendNotifications(t, s)
+		}
+		notifications = prNotifications(t, db, entities.ChannelEmail, pr.ID)
+		if n := notifications[0]; n.status != entities.NotificationDead || n.attempts != notificationMaxAttempts {
+			t.Fatalf("notification = %+v, want dead after %d attempts", n, notificationMaxAttempts)
+		}
+		if emails := takeEmails(t, srv, &seen, name); len(emails) != 0 {
+			t.Fatalf("server accepted %d emails while rejecting", len(emails))
+		}
+
+		srv.Reject("")
+		if _, err := s.RetryNotification(notifications[0].id); err != nil {
+			t.Fatalf("RetryNotification: %v", err)
+		}
+		sendNotifications(t, s)
+
+		if emails := takeEmails(t, srv, &seen, name); len(emails) != 1 {
+			t.Fatalf("emails after retry = %d, want 1", len(emails))
+		}
+		if n := prNotifications(t, db, entities.ChannelEmail, pr.ID)[0]; n.status != entities.NotificationSent {
+			t.Fatalf("notification after retry = %+v, want sent", n)
+		}
+	})
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// runPeriodically calls job every interval until ctx is cancelled and logs
+// how many items it processed.
+func runPeriodically(ctx context.Context, interval time.Duration, what string, job func() (int, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := job()
+			if err != nil {
+				log.Printf("Periodic job failed (%s): %v", what, err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("Periodic job: %s: %d", what, n)
+			}
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+const (
+	notificationBatchSize   = 50
+	notificationLease       = time.Minute
+	notificationMaxAttempts = 6
+
+	defaultNotificationPageSize = 100
+	maxNotificationPageSize     = 500
+)
+
+// ListNotifications returns one page of notifications and the cursor of the
+// next page, which is empty when there are no more notifications.
+func (s *Service) ListNotifications(filter entities.NotificationFilter) ([]entities.Notification, string, error) {
+	switch filter.Status {
+	case "", entities.NotificationPending, entities.NotificationSent, entities.NotificationDead:
+	default:
+		return nil, "", errors.New(entities.ErrBadRequest)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultNotificationPageSize
+	}
+	if filter.Limit > maxNotificationPageSize {
+		filter.Limit = maxNotificationPageSize
+	}
+
+	notifications, err := s.repo.ListNotifications(filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if len(notifications) == filter.Limit {
+		nextCursor = strconv.FormatInt(notifications[len(notifications)-1].ID, 10)
+	}
+
+	return notifications, nextCursor, nil
+}
+
+// RetryNotification puts a notification, typically a dead one, back in the
+// queue with a fresh retry budget.
+func (s *Service) RetryNotification(id int64) (*entities.Notification, error) {
+	err := s.repo.RequeueNotification(id)
+	if err == sql.ErrNoRows {
+		return nil, errors.New(entities.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetNotification(id)
+}
+
+// groupNotifications splits claimed notifications into messages: each digest
+// on its own, events by recipient and chatEventKey in the order they were
+// queued.
+func groupNotifications(notifications []entities.Notification, events map[int64]*entities.PREvent) [][]entities.Notification {
+	var groups [][]entities.Notification
+	index := make(map[string]int)
+	for _, n := range notifications {
+		key := fmt.Sprintf("%s|%d", n.Channel, n.ID)
+		if n.EventID != nil {
+			if e := events[*n.EventID]; e != nil {
+				key = n.Channel + "|" + n.UserID + "|" + chatEventKey(e)
+			}
+		}
+
+		if i, ok := index[key]; ok {
+			groups[i] = append(groups[i], n)
+			continue
+		}
+		index[key] = len(groups)
+		groups = append(groups, []entities.Notification{n})
+	}
+	return groups
+}
+
+// SendNotifications queues due daily digests and sends due notifications.
+// Failed messages are retried with exponential backoff and dead-lettered
+// after notificationMaxAttempts. It returns the number of notifications sent.
+func (s *Service) SendNotifications() (int, error) {
+	now := time.Now()
+	if _, err := s.repo.EnqueueChatDigests(now); err != nil {
+		return 0, err
+	}
+
+	channels := []string{entities.ChannelChat}
+	if s.mailer != nil {
+		if _, err := s.EnqueueEmailDigests(now); err != nil {
+			return 0, err
+		}
+		channels = append(channels, entities.ChannelEmail)
+	}
+
+	notifications, err := s.repo.ClaimDueNotifications(channels, notificationBatchSize, notificationLease)
+	if err != nil || len(notifications) == 0 {
+		return 0, err
+	}
+
+	var eventIDs []int64
+	for _, n := range notifications {
+		if n.EventID != nil {
+			eventIDs = append(eventIDs, *n.EventID)
+		}
+	}
+	events, err := s.repo.GetEvents(eventIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, group := range groupNotifications(notifications, events) {
+		var sendErr error
+		switch group[0].Channel {
+		case entities.ChannelChat:
+			sendErr = s.sendChat(group, events)
+		case entities.ChannelEmail:
+			sendErr = s.sendEmail(group, events)
+		default:
+			sendErr = fmt.Errorf("unknown channel %q", group[0].Channel)
+		}
+
+		for _, n := range group {
+			if sendErr == nil {
+				err = s.repo.MarkNotificationSent(n.ID)
+				if err == nil {
+					sent++
+				}
+			} else {
+				var retryIn *time.Duration
+				if n.Attempts+1 < notificationMaxAttempts {
+					backoff := webhookBackoff(n.Attempts + 1)
+					retryIn = &backoff
+				} else {
+					log.Printf("Notification %d is dead after %d attempts: %v", n.ID, n.Attempts+1, sendErr)
+				}
+				err = s.repo.MarkNotificationFailed(n.ID, sendErr.Error(), retryIn)
+			}
+			if err != nil && err != sql.ErrNoRows {
+				return sent, err
+			}
+		}
+	}
+
+	return sent, nil
+}
+
+func (s *Service) RunNotifications(ctx context.Context, interval time.Duration) {
+	runPeriodically(ctx, interval, "notifications", s.SendNotifications)
+}
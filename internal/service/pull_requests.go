@@ -0,0 +1,94 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+const (
+	defaultPRPageSize = 50
+	maxPRPageSize     = 200
+)
+
+func (s *Service) GetPullRequest(prID string) (*entities.PullRequest, error) {
+	pr, err := s.repo.GetPullRequest(prID)
+	if err == sql.ErrNoRows {
+		return nil, errors.New(entities.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if pr.AssignedReviewers == nil {
+		pr.AssignedReviewers = []string{}
+	}
+	return pr, nil
+}
+
+// ListPullRequests returns one page of pull requests and the cursor of the
+// next page, which is empty on the last page.
+func (s *Service) ListPullRequests(filter entities.PRFilter, cursor string) ([]entities.PullRequest, string, error) {
+	switch filter.Status {
+	case "", entities.StatusOpen, entities.StatusMerged, entities.StatusClosed:
+	default:
+		return nil, "", errors.New(entities.ErrBadRequest)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultPRPageSize
+	}
+	if filter.Limit > maxPRPageSize {
+		filter.Limit = maxPRPageSize
+	}
+
+	if cursor != "" {
+		createdAt, id, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, "", errors.New(entities.ErrBadRequest)
+		}
+		filter.AfterCreatedAt = &createdAt
+		filter.AfterID = id
+	}
+
+	prs, err := s.repo.ListPullRequests(filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if len(prs) == filter.Limit {
+		last := prs[len(prs)-1]
+		if last.CreatedAt != nil {
+			nextCursor = encodeCursor(*last.CreatedAt, last.ID)
+		}
+	}
+
+	return prs, nextCursor, nil
+}
+
+func encodeCursor(createdAt time.Time, id string) string {
+	raw := createdAt.UTC().Format(time.RFC3339Nano) + "|" + id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+
+	ts, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return time.Time{}, "", errors.New("malformed cursor")
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+
+	return createdAt, id, nil
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+// AddReviewer assigns a hand-picked reviewer to an open PR. The reviewer must
+// be an active member of the author's team, must not be the author or have
+// declined the PR, and the PR must stay within the reviewer limit.
+func (s *Service) AddReviewer(prID, userID, actor string) (*entities.PullRequest, error) {
+	pr, err := s.repo.GetPullRequest(prID)
+	if err == sql.ErrNoRows {
+		return nil, errors.New(entities.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if pr.Status == entities.StatusMerged {
+		return nil, errors.New(entities.ErrPRMerged)
+	}
+	if pr.Status == entities.StatusClosed {
+		return nil, errors.New(entities.ErrPRClosed)
+	}
+
+	user, err := s.repo.GetUser(userID)
+	if err == sql.ErrNoRows {
+		return nil, errors.New(entities.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	author, err := s.repo.GetUser(pr.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkManualReviewer(pr, user, author); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.AddReviewer(prID, userID, entities.PREvent{
+		Type:          entities.EventReviewerAdded,
+		PullRequestID: prID,
+		NewUserID:     userID,
+		TeamName:      author.TeamName,
+		Actor:         actor,
+	}); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetPullRequest(prID)
+}
+
+func (s *Service) checkManualReviewer(pr *entities.PullRequest, user, author *entities.User) error {
+	noCandidate := func(detail string) error {
+		return &entities.DomainError{Code: entities.ErrNoCandidate, Detail: detail}
+	}
+
+	if user.ID == pr.AuthorID {
+		return noCandidate("author cannot review their own PR")
+	}
+	if user.TeamName != author.TeamName {
+		return noCandidate("reviewer is not a member of the author's team")
+	}
+	if !user.IsActive {
+		return noCandidate("reviewer is not active")
+	}
+	for _, reviewerID := range pr.AssignedReviewers {
+		if reviewerID == user.ID {
+			return noCandidate("reviewer is already assigned to this PR")
+		}
+	}
+	if len(pr.AssignedReviewers) >= s.maxReviewers {
+		return noCandidate("PR already has the maximum of " + strconv.Itoa(s.maxReviewers) + " reviewers")
+	}
+
+	decliners, err := s.repo.GetPRDecliners(pr.ID)
+	if err != nil {
+		return err
+	}
+	for _, id := range decliners {
+		if id == user.ID {
+			return noCandidate("reviewer has declined this PR")
+		}
+	}
+
+	return nil
+}
+
+// RemoveReviewer drops a reviewer from an open PR without a replacement.
+func (s *Service) RemoveReviewer(prID, userID, actor string) (*entities.PullRequest, error) {
+	_, user, err := s.loadAssignment(prID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.RemoveReviewer(prID, userID, entities.PREvent{
+		Type:          entities.EventReviewerRemoved,
+		PullRequestID: prID,
+		OldUserID:     userID,
+		TeamName:      user.TeamName,
+		Actor:         actor,
+	})
+	if err == sql.ErrNoRows {
+		return nil, errors.New(entities.ErrNotAssigned)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetPullRequest(prID)
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+	"github.com/alexalexbor04/pull_request_service/internal/repos"
+	"github.com/alexalexbor04/pull_request_service/internal/scm"
+)
+
+const (
+	SCMResultCreated   = "created"
+	SCMResultMerged    = "merged"
+	SCMResultClosed    = "closed"
+	SCMResultReopened  = "reopened"
+	SCMResultIgnored   = "ignored"
+	SCMResultDuplicate = "duplicate"
+)
+
+const reasonClosedUpstream = "closed_upstream"
+
+func validProvider(provider string) bool {
+	for _, p := range scm.Providers {
+		if p == provider {
+			return true
+		}
+	}
+	return false
+}
+
+// LinkLogin maps a login on a code hosting service to a user. Logins are
+// case-insensitive and stored lower-cased.
+func (s *Service) LinkLogin(l *entities.UserLogin, actor string) error {
+	l.Login = strings.ToLower(strings.TrimSpace(l.Login))
+	if !validProvider(l.Provider) || l.Login == "" {
+		return errors.New(entities.ErrBadRequest)
+	}
+
+	user, err := s.repo.GetUser(l.UserID)
+	if err == sql.ErrNoRows {
+		return errors.New(entities.ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.repo.LinkLogin(l, entities.PREvent{
+		Type:     entities.EventUserLoginLinked,
+		UserID:   l.UserID,
+		TeamName: user.TeamName,
+		Actor:    actor,
+		Details:  map[string]string{"provider": l.Provider, "login": l.Login},
+	})
+}
+
+func (s *Service) UnlinkLogin(provider, login, actor string) error {
+	login = strings.ToLower(strings.TrimSpace(login))
+	userID, err := s.repo.GetUserIDByLogin(provider, login)
+	if err == sql.ErrNoRows {
+		return errors.New(entities.ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.UnlinkLogin(provider, login, entities.PREvent{
+		Type:    entities.EventUserLoginUnlinked,
+		UserID:  userID,
+		Actor:   actor,
+		Details: map[string]string{"provider": provider, "login": login},
+	})
+	if err == sql.ErrNoRows {
+		return errors.New(entities.ErrNotFound)
+	}
+	return err
+}
+
+func (s *Service) GetUserLogins(userID string) ([]entities.UserLogin, error) {
+	if _, err := s.repo.GetUser(userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New(entities.ErrNotFound)
+		}
+		return nil, err
+	}
+
+	return s.repo.GetUserLogins(userID)
+}
+
+// resolveLogin finds the user behind a provider login: a linked login first,
+// then a user whose id equals the login.
+func (s *Service) resolveLogin(provider, login string) (string, error) {
+	userID, err := s.repo.GetUserIDByLogin(provider, strings.ToLower(login))
+	if err == nil {
+		return userID, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", err
+	}
+
+	if _, err := s.repo.GetUser(login); err == nil {
+		return login, nil
+	} else if err != sql.ErrNoRows {
+		return "", err
+	}
+
+	return "", &entities.DomainError{Code: entities.ErrNotFound, Detail: "no user linked to " + provider + " login " + login}
+}
+
+// HandleSCMChange applies a pull request change received from a code hosting
+// service. A delivery is recorded in the transaction of the change it
+// carries, so it is processed at most once and a failed one can be retried
+// by the provider; replaying a change that is already reflected is a no-op.
+func (s *Service) HandleSCMChange(provider, deliveryID string, change *scm.Change) (string, error) {
+	tracked := s
+	if deliveryID != "" {
+		seen, err := s.repo.InboundDeliverySeen(provider, deliveryID)
+		if err != nil {
+			return "", err
+		}
+		if seen {
+			return SCMResultDuplicate, nil
+		}
+		tracked = s.withRepo(s.repo.WithInboundDelivery(provider, deliveryID))
+	}
+	if change == nil {
+		return SCMResultIgnored, nil
+	}
+
+	result, err := s.applySCMChange(tracked, provider, change)
+	if errors.Is(err, repos.ErrDuplicateDelivery) {
+		return SCMResultDuplicate, nil
+	}
+	return result, err
+}
+
+// applySCMChange makes the final write of a change through tracked, which
+// records the delivery with it.
+func (s *Service) applySCMChange(tracked *Service, provider string, change *scm.Change) (string, error) {
+	prID := change.PullRequestID(provider)
+	actor := provider + ":" + change.SenderLogin
+
+	pr, err := s.repo.GetPullRequest(prID)
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+	exists := err == nil
+
+	switch change.Action {
+	case scm.ActionOpen, scm.ActionReopen:
+		if change.Draft {
+			return SCMResultIgnored, nil
+		}
+		if !exists {
+			// Without a known author the PR is created by a later event
+			// of its author rather than assigned to the wrong person.
+			if change.AuthorLogin == "" {
+				return SCMResultIgnored, nil
+			}
+			authorID, err := s.resolveLogin(provider, change.AuthorLogin)
+			if err != nil {
+				return "", err
+			}
+			_, err = tracked.CreatePullRequest(prID, change.Title, authorID)
+			if err != nil && err.Error() == entities.ErrPRExists {
+				// Created by another delivery since the lookup above.
+				return SCMResultIgnored, nil
+			}
+			if err != nil {
+				return "", err
+			}
+			return SCMResultCreated, nil
+		}
+		if change.Action == scm.ActionReopen && pr.Status == entities.StatusClosed {
+			if _, err := tracked.ReopenPullRequest(prID, actor); err != nil {
+				return "", err
+			}
+			return SCMResultReopened, nil
+		}
+
+	case scm.ActionMerge:
+		if !exists || pr.Status == entities.StatusMerged {
+			return SCMResultIgnored, nil
+		}
+		// The delivery is recorded with the merge, so a retry after a
+		// failed merge finds the PR reopened and merges it.
+		if pr.Status == entities.StatusClosed {
+			if _, err := s.ReopenPullRequest(prID, actor); err != nil {
+				return "", err
+			}
+		}
+		if _, err := tracked.MergePullRequest(prID); err != nil {
+			return "", err
+		}
+		return SCMResultMerged, nil
+
+	case scm.ActionClose:
+		if !exists || pr.Status != entities.StatusOpen {
+			return SCMResultIgnored, nil
+		}
+		if _, err := tracked.ClosePullRequest(prID, reasonClosedUpstream, actor); err != nil {
+			return "", err
+		}
+		return SCMResultClosed, nil
+	}
+
+	return SCMResultIgnored, nil
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+const sampleRuns = 200000
+
+// sampleFrequencies runs weightedSample many times and returns how often
+// every index was picked.
+func sampleFrequencies(t *testing.T, rnd *rand.Rand, weights []float64, k int) []float64 {
+	t.Helper()
+
+	counts := make([]int, len(weights))
+	for run := 0; run < sampleRuns; run++ {
+		picked := weightedSample(rnd, weights, k)
+
+		want := k
+		if want > len(weights) {
+			want = len(weights)
+		}
+		if len(picked) != want {
+			t.Fatalf("weightedSample picked %d of %d weights, want %d", len(picked), len(weights), want)
+		}
+
+		seen := make(map[int]bool, len(picked))
+		for _, idx := range picked {
+			if seen[idx] {
+				t.Fatalf("weightedSample picked index %d twice: %v", idx, picked)
+			}
+			seen[idx] = true
+			counts[idx]++
+		}
+	}
+
+	freqs := make([]float64, len(weights))
+	for i, c := range counts {
+		freqs[i] = float64(c) / sampleRuns
+	}
+	return freqs
+}
+
+func antiAffinityWeights(reviewWeights []float64, reviewed []int, window int) []float64 {
+	weights := make([]float64, len(reviewWeights))
+	for i, w := range reviewWeights {
+		weights[i] = w * antiAffinityFactor(reviewed[i], float64(window))
+	}
+	return weights
+}
+
+func TestAntiAffinityFactor(t *testing.T) {
+	for window := 1; window <= 20; window++ {
+		prev := antiAffinityFactor(0, float64(window))
+		if prev != 1 {
+			t.Fatalf("window %d: factor for no recent reviews = %v, want 1", window, prev)
+		}
+		for reviewed := 1; reviewed <= window+3; reviewed++ {
+			f := antiAffinityFactor(reviewed, float64(window))
+			if f <= 0 || f > 1 {
+				t.Fatalf("window %d, reviewed %d: factor %v outside (0, 1]", window, reviewed, f)
+			}
+			if f > prev {
+				t.Fatalf("window %d: factor grows from %v to %v at %d reviews", window, prev, f, reviewed)
+			}
+			prev = f
+		}
+	}
+}
+
+func TestWeightedSampleMatchesInclusionProbabilities(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	cases := []struct {
+		name    string
+		weights []float64
+		k       int
+	}{
+		{"uniform", []float64{1, 1, 1, 1, 1}, 2},
+		{"anti-affinity", antiAffinityWeights([]float64{1, 1, 1, 1, 1}, []int{0, 1, 2, 4, 5}, 5), 2},
+		{"anti-affinity and review weight", antiAffinityWeights([]float64{0.5, 2, 1, 1.5}, []int{3, 0, 1, 2}, 3), 2},
+		{"fewer candidates than k", []float64{1, 0.25}, 3},
+	}
+
+	// Random cases cover weight mixes that nobody wrote down by hand.
+	for i := 0; i < 5; i++ {
+		n := 2 + rnd.Intn(5)
+		window := 1 + rnd.Intn(10)
+		reviewWeights := make([]float64, n)
+		reviewed := make([]int, n)
+		for j := range reviewWeights {
+			reviewWeights[j] = 0.1 + 3*rnd.Float64()
+			reviewed[j] = rnd.Intn(window + 1)
+		}
+		cases = append(cases, struct {
+			name    string
+			weights []float64
+			k       int
+		}{"random", antiAffinityWeights(reviewWeights, reviewed, window), 1 + rnd.Intn(3)})
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			freqs := sampleFrequencies(t, rnd, tc.weights, tc.k)
+			probs := inclusionProbabilities(tc.weights, tc.k)
+			for i := range tc.weights {
+				// Five standard deviations of the binomial frequency.
+				tolerance := 5 * math.Sqrt(probs[i]*(1-probs[i])/sampleRuns)
+				if tolerance < 1e-3 {
+					tolerance = 1e-3
+				}
+				if math.Abs(freqs[i]-probs[i]) > tolerance {
+					t.Errorf("weights %v, k %d: index %d picked with frequency %.4f, want %.4f ± %.4f",
+						tc.weights, tc.k, i, freqs[i], probs[i], tolerance)
+				}
+			}
+		})
+	}
+}
+
+func TestAntiAffinitySpreadsReviews(t *testing.T) {
+	rnd := rand.New(rand.NewSource(2))
+	const window = 5
+
+	// Equal review weights; only the number of the author's last five pull
+	// requests each candidate reviewed differs.
+	reviewed := []int{0, 1, 3, 5}
+	weights := antiAffinityWeights([]float64{1, 1, 1, 1}, reviewed, window)
+
+	probs := inclusionProbabilities(weights, reviewersPerPR)
+	freqs := sampleFrequencies(t, rnd, weights, reviewersPerPR)
+	for i := 1; i < len(reviewed); i++ {
+		if probs[i] >= probs[i-1] {
+			t.Errorf("candidate with %d recent reviews has probability %.4f, not below %.4f of one with %d",
+				reviewed[i], probs[i], probs[i-1], reviewed[i-1])
+		}
+		if freqs[i] >= freqs[i-1] {
+			t.Errorf("candidate with %d recent reviews picked with frequency %.4f, not below %.4f of one with %d",
+				reviewed[i], freqs[i], freqs[i-1], reviewed[i-1])
+		}
+	}
+
+	uniform := inclusionProbabilities([]float64{1, 1, 1, 1}, reviewersPerPR)
+	if probs[0] <= uniform[0] {
+		t.Errorf("fresh candidate probability %.4f is not above %.4f without anti-affinity", probs[0], uniform[0])
+	}
+	if probs[len(probs)-1] >= uniform[len(uniform)-1] {
+		t.Errorf("busiest candidate probability %.4f is not below %.4f without anti-affinity",
+			probs[len(probs)-1], uniform[len(uniform)-1])
+	}
+}
@@ -3,23 +3,63 @@ package service
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"math/rand"
+	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/alexalexbor04/pull_request_service/internal/entities"
+	"github.com/alexalexbor04/pull_request_service/internal/mail"
 	"github.com/alexalexbor04/pull_request_service/internal/repos"
 )
 
+const reviewersPerPR = 2
+
 type Service struct {
-	repo *repos.Repo
-	rand *rand.Rand
+	repo       *repos.Repo
+	rand       *rand.Rand
+	httpClient *http.Client
+	mailer     *mail.Mailer
+	eventHub   *eventHub
+	webhookKey []byte
+
+	antiAffinityWindow int
+	maxReviewers       int
 }
 
 func New(repo *repos.Repo) *Service {
 	return &Service{
-		repo: repo,
-		rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+		repo:         repo,
+		rand:         rand.New(rand.NewSource(time.Now().UnixNano())),
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+		eventHub:     newEventHub(),
+		maxReviewers: reviewersPerPR,
+	}
+}
+
+// withRepo returns a copy of s that reads and writes through repo.
+func (s *Service) withRepo(repo *repos.Repo) *Service {
+	c := *s
+	c.repo = repo
+	return &c
+}
+
+// SetAntiAffinityWindow enables the anti-affinity policy over the author's
+// last n pull requests. Zero disables it.
+func (s *Service) SetAntiAffinityWindow(n int) {
+	s.antiAffinityWindow = n
+}
+
+// SetMaxReviewers limits how many reviewers can be added to a PR by hand. It
+// cannot be lower than the reviewersPerPR assigned automatically, or topped
+// up pull requests would be over the limit.
+func (s *Service) SetMaxReviewers(n int) error {
+	if n < reviewersPerPR {
+		return fmt.Errorf("at least %d reviewers are assigned automatically", reviewersPerPR)
 	}
+	s.maxReviewers = n
+	return nil
 }
 
 func (s *Service) CreateTeam(team *entities.Team) error {
@@ -31,7 +71,10 @@ func (s *Service) CreateTeam(team *entities.Team) error {
 		return errors.New(entities.ErrTeamExists)
 	}
 
-	if err := s.repo.CreateTeam(team.TeamName); err != nil {
+	if err := s.repo.CreateTeam(team.TeamName, entities.PREvent{
+		Type:     entities.EventTeamCreated,
+		TeamName: team.TeamName,
+	}); err != nil {
 		return err
 	}
 
@@ -42,11 +85,23 @@ func (s *Service) CreateTeam(team *entities.Team) error {
 			TeamName: team.TeamName,
 			IsActive: member.IsActive,
 		}
-		if err := s.repo.CreateOrUpdateUser(user); err != nil {
+		if err := s.repo.CreateOrUpdateUser(user, entities.PREvent{
+			Type:     entities.EventUserUpserted,
+			UserID:   user.ID,
+			TeamName: user.TeamName,
+			Details: map[string]string{
+				"username":  user.Username,
+				"is_active": strconv.FormatBool(user.IsActive),
+			},
+		}); err != nil {
 			return err
 		}
 	}
 
+	// Members can move in from other teams together with the open pull
+	// requests they authored, which the new team's members now review.
+	s.topUpTeam(team.TeamName)
+
 	return nil
 }
 
@@ -67,11 +122,24 @@ func (s *Service) SetUserActive(userID string, isActive bool) (*entities.User, e
 		return nil, err
 	}
 
-	if err := s.repo.SetUserActive(userID, isActive); err != nil {
+	if err := s.repo.SetUserActive(userID, isActive, entities.PREvent{
+		Type:     entities.EventUserActiveChanged,
+		UserID:   userID,
+		TeamName: user.TeamName,
+		Details: map[string]string{
+			"from": strconv.FormatBool(user.IsActive),
+			"to":   strconv.FormatBool(isActive),
+		},
+	}); err != nil {
 		return nil, err
 	}
 
 	user.IsActive = isActive
+
+	if isActive {
+		s.topUpTeam(user.TeamName)
+	}
+
 	return user, nil
 }
 
@@ -97,7 +165,12 @@ func (s *Service) CreatePullRequest(prID, prName, authorID string) (*entities.Pu
 		return nil, err
 	}
 
-	reviewers := s.selectRandomReviewers(candidates, 2)
+	weights, err := s.selectionWeights(authorID, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	reviewers := s.selectRandomReviewers(candidates, weights, reviewersPerPR)
 	reviewerIDs := make([]string, len(reviewers))
 	for i, r := range reviewers {
 		reviewerIDs[i] = r.ID
@@ -109,32 +182,154 @@ func (s *Service) CreatePullRequest(prID, prName, authorID string) (*entities.Pu
 		AuthorID:          authorID,
 		Status:            entities.StatusOpen,
 		AssignedReviewers: reviewerIDs,
-	}
-
-	if err := s.repo.CreatePullRequest(pr, reviewerIDs); err != nil {
+		Labels:            []string{},
+	}
+
+	events := []entities.PREvent{{
+		Type:          entities.EventPRCreated,
+		PullRequestID: prID,
+		UserID:        authorID,
+		TeamName:      author.TeamName,
+		Details:       map[string]string{"name": prName},
+	}}
+	for _, reviewerID := range reviewerIDs {
+		events = append(events, entities.PREvent{
+			Type:          entities.EventReviewerAssigned,
+			PullRequestID: prID,
+			NewUserID:     reviewerID,
+			TeamName:      author.TeamName,
+		})
+	}
+
+	if err := s.repo.CreatePullRequest(pr, reviewerIDs, events...); err != nil {
 		return nil, err
 	}
 
 	return s.repo.GetPullRequest(prID)
 }
 
-func (s *Service) selectRandomReviewers(candidates []entities.User, maxCount int) []entities.User {
+// selectRandomReviewers draws up to maxCount distinct candidates, each draw
+// picking a remaining candidate with probability proportional to its weight.
+func (s *Service) selectRandomReviewers(candidates []entities.User, weights []float64, maxCount int) []entities.User {
 	if len(candidates) == 0 {
 		return []entities.User{}
 	}
 
-	count := maxCount
-	if len(candidates) < count {
-		count = len(candidates)
+	picked := weightedSample(s.rand, weights, maxCount)
+	selected := make([]entities.User, len(picked))
+	for i, idx := range picked {
+		selected[i] = candidates[idx]
+	}
+
+	return selected
+}
+
+// selectionWeights returns the sampling weight of every candidate. With the
+// anti-affinity policy enabled, the review_weight of a candidate who reviewed
+// c of the author's last N pull requests is scaled by (N+1-c)/(N+1).
+func (s *Service) selectionWeights(authorID string, candidates []entities.User) ([]float64, error) {
+	weights := make([]float64, len(candidates))
+	for i, c := range candidates {
+		weights[i] = candidateWeight(c)
+	}
+
+	if s.antiAffinityWindow <= 0 || len(candidates) == 0 {
+		return weights, nil
 	}
 
-	shuffled := make([]entities.User, len(candidates))
-	copy(shuffled, candidates)
-	s.rand.Shuffle(len(shuffled), func(i, j int) {
-		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
-	})
+	counts, err := s.repo.GetRecentReviewCounts(authorID, s.antiAffinityWindow)
+	if err != nil {
+		return nil, err
+	}
 
-	return shuffled[:count]
+	window := float64(s.antiAffinityWindow)
+	for i, c := range candidates {
+		weights[i] *= antiAffinityFactor(counts[c.ID], window)
+	}
+
+	return weights, nil
+}
+
+func antiAffinityFactor(reviewed int, window float64) float64 {
+	if reviewed <= 0 {
+		return 1
+	}
+	if float64(reviewed) > window {
+		reviewed = int(window)
+	}
+	return (window + 1 - float64(reviewed)) / (window + 1)
+}
+
+func candidateWeight(u entities.User) float64 {
+	if u.ReviewWeight <= 0 {
+		return 1
+	}
+	return u.ReviewWeight
+}
+
+// weightedSample returns indexes of up to k weights sampled without replacement.
+func weightedSample(rnd *rand.Rand, weights []float64, k int) []int {
+	remaining := make([]int, len(weights))
+	total := 0.0
+	for i, w := range weights {
+		remaining[i] = i
+		total += w
+	}
+	if k > len(remaining) {
+		k = len(remaining)
+	}
+
+	picked := make([]int, 0, k)
+	for len(picked) < k {
+		target := rnd.Float64() * total
+		pos := len(remaining) - 1
+		for i, idx := range remaining {
+			target -= weights[idx]
+			if target < 0 {
+				pos = i
+				break
+			}
+		}
+
+		idx := remaining[pos]
+		picked = append(picked, idx)
+		total -= weights[idx]
+		remaining = append(remaining[:pos], remaining[pos+1:]...)
+	}
+
+	return picked
+}
+
+// inclusionProbabilities returns, for every weight, the probability that
+// weightedSample with the same k picks it.
+func inclusionProbabilities(weights []float64, k int) []float64 {
+	probs := make([]float64, len(weights))
+	used := make([]bool, len(weights))
+
+	var walk func(p, total float64, depth int)
+	walk = func(p, total float64, depth int) {
+		if depth == k || total <= 0 {
+			return
+		}
+		for i, w := range weights {
+			if used[i] {
+				continue
+			}
+			q := p * w / total
+			probs[i] += q
+			used[i] = true
+			walk(q, total-w, depth+1)
+			used[i] = false
+		}
+	}
+
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	walk(1, total, 0)
+
+	return probs
 }
 
 func (s *Service) MergePullRequest(prID string) (*entities.PullRequest, error) {
@@ -149,74 +344,184 @@ func (s *Service) MergePullRequest(prID string) (*entities.PullRequest, error) {
 	if pr.Status == entities.StatusMerged {
 		return pr, nil
 	}
+	if pr.Status == entities.StatusClosed {
+		return nil, errors.New(entities.ErrPRClosed)
+	}
 
-	now := time.Now()
-	if err := s.repo.UpdatePRStatus(prID, entities.StatusMerged, &now); err != nil {
+	now := time.Now().UTC()
+	author, err := s.repo.GetUser(pr.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdatePRStatus(prID, entities.StatusMerged, &now, entities.PREvent{
+		Type:          entities.EventPRMerged,
+		PullRequestID: prID,
+		TeamName:      author.TeamName,
+		Details: map[string]string{
+			"from": pr.Status,
+			"to":   entities.StatusMerged,
+		},
+	}); err != nil {
 		return nil, err
 	}
 
 	return s.repo.GetPullRequest(prID)
 }
 
-func (s *Service) ReassignReviewer(prID, oldUserID string) (*entities.PullRequest, string, error) {
+// ReassignReviewer replaces oldUserID with a random candidate from their team.
+// reason and actor are recorded in the PR history.
+func (s *Service) ReassignReviewer(prID, oldUserID, reason, actor string) (*entities.PullRequest, string, error) {
+	pr, oldUser, err := s.loadAssignment(prID, oldUserID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	newReviewer, err := s.pickReplacement(pr, oldUser)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := s.repo.ReplaceReviewer(prID, oldUserID, newReviewer.ID, entities.PREvent{
+		Type:          entities.EventReviewerReassigned,
+		PullRequestID: prID,
+		OldUserID:     oldUserID,
+		NewUserID:     newReviewer.ID,
+		TeamName:      oldUser.TeamName,
+		Reason:        reason,
+		Actor:         actor,
+	}); err != nil {
+		return nil, "", err
+	}
+
+	updatedPR, err := s.repo.GetPullRequest(prID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return updatedPR, newReviewer.ID, nil
+}
+
+// loadAssignment checks that userID is a reviewer of the open PR prID.
+func (s *Service) loadAssignment(prID, userID string) (*entities.PullRequest, *entities.User, error) {
 	pr, err := s.repo.GetPullRequest(prID)
 	if err == sql.ErrNoRows {
-		return nil, "", errors.New(entities.ErrNotFound)
+		return nil, nil, errors.New(entities.ErrNotFound)
 	}
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
 
 	if pr.Status == entities.StatusMerged {
-		return nil, "", errors.New(entities.ErrPRMerged)
+		return nil, nil, errors.New(entities.ErrPRMerged)
+	}
+	if pr.Status == entities.StatusClosed {
+		return nil, nil, errors.New(entities.ErrPRClosed)
 	}
 
 	isAssigned := false
 	for _, reviewerID := range pr.AssignedReviewers {
-		if reviewerID == oldUserID {
+		if reviewerID == userID {
 			isAssigned = true
 			break
 		}
 	}
 	if !isAssigned {
-		return nil, "", errors.New(entities.ErrNotAssigned)
+		return nil, nil, errors.New(entities.ErrNotAssigned)
 	}
 
-	oldUser, err := s.repo.GetUser(oldUserID)
+	user, err := s.repo.GetUser(userID)
 	if err == sql.ErrNoRows {
-		return nil, "", errors.New(entities.ErrNotFound)
+		return nil, nil, errors.New(entities.ErrNotFound)
 	}
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
 
+	return pr, user, nil
+}
+
+// pickReplacement selects a new reviewer for pr from oldUser's team, skipping
+// the author, current reviewers and everyone who declined this PR.
+func (s *Service) pickReplacement(pr *entities.PullRequest, oldUser *entities.User) (*entities.User, error) {
 	excludeIDs := append([]string{pr.AuthorID}, pr.AssignedReviewers...)
 
+	decliners, err := s.repo.GetPRDecliners(pr.ID)
+	if err != nil {
+		return nil, err
+	}
+	excludeIDs = append(excludeIDs, decliners...)
+
 	candidates, err := s.repo.GetActiveTeamMembers(oldUser.TeamName, excludeIDs)
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
 
 	if len(candidates) == 0 {
-		return nil, "", errors.New(entities.ErrNoCandidate)
+		return nil, s.noCandidateError(oldUser.TeamName, excludeIDs)
 	}
 
-	newReviewer := s.selectRandomReviewers(candidates, 1)[0]
+	weights, err := s.selectionWeights(pr.AuthorID, candidates)
+	if err != nil {
+		return nil, err
+	}
 
-	if err := s.repo.ReplaceReviewer(prID, oldUserID, newReviewer.ID); err != nil {
-		return nil, "", err
+	newReviewer := s.selectRandomReviewers(candidates, weights, 1)[0]
+	return &newReviewer, nil
+}
+
+// noCandidateError explains an empty candidate list: when someone is only
+// excluded by their max_open_reviews limit the detail says so.
+func (s *Service) noCandidateError(teamName string, excludeIDs []string) error {
+	atCapacity, err := s.repo.CountTeamMembersAtCapacity(teamName, excludeIDs)
+	if err != nil {
+		return err
+	}
+	if atCapacity > 0 {
+		return &entities.DomainError{
+			Code:   entities.ErrNoCandidate,
+			Detail: "all available candidates in team are at their max_open_reviews capacity",
+		}
 	}
+	return errors.New(entities.ErrNoCandidate)
+}
 
-	updatedPR, err := s.repo.GetPullRequest(prID)
+func (s *Service) SetUserMaxOpenReviews(userID string, maxOpenReviews *int) (*entities.User, error) {
+	if maxOpenReviews != nil && *maxOpenReviews < 0 {
+		return nil, errors.New(entities.ErrBadRequest)
+	}
+
+	user, err := s.repo.GetUser(userID)
+	if err == sql.ErrNoRows {
+		return nil, errors.New(entities.ErrNotFound)
+	}
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
 
-	return updatedPR, newReviewer.ID, nil
+	limit := "unlimited"
+	if maxOpenReviews != nil {
+		limit = strconv.Itoa(*maxOpenReviews)
+	}
+
+	if err := s.repo.SetUserMaxOpenReviews(userID, maxOpenReviews, entities.PREvent{
+		Type:     entities.EventUserCapacityChanged,
+		UserID:   userID,
+		TeamName: user.TeamName,
+		Details:  map[string]string{"max_open_reviews": limit},
+	}); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetUser(userID)
 }
 
-func (s *Service) GetUserReviews(userID string) ([]entities.PullRequestShort, error) {
-	_, err := s.repo.GetUser(userID)
+func (s *Service) SetUserReviewWeight(userID string, weight float64) (*entities.User, error) {
+	if weight <= 0 {
+		return nil, errors.New(entities.ErrBadRequest)
+	}
+
+	user, err := s.repo.GetUser(userID)
 	if err == sql.ErrNoRows {
 		return nil, errors.New(entities.ErrNotFound)
 	}
@@ -224,7 +529,194 @@ func (s *Service) GetUserReviews(userID string) ([]entities.PullRequestShort, er
 		return nil, err
 	}
 
-	return s.repo.GetUserReviews(userID)
+	if err := s.repo.SetUserReviewWeight(userID, weight, entities.PREvent{
+		Type:     entities.EventUserWeightChanged,
+		UserID:   userID,
+		TeamName: user.TeamName,
+		Details:  map[string]string{"review_weight": strconv.FormatFloat(weight, 'f', -1, 64)},
+	}); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetUser(userID)
+}
+
+// GetReviewerProbabilities reports how likely each current candidate is to be
+// assigned to a new PR from authorID.
+func (s *Service) GetReviewerProbabilities(authorID string) ([]entities.CandidateProbability, error) {
+	author, err := s.repo.GetUser(authorID)
+	if err == sql.ErrNoRows {
+		return nil, errors.New(entities.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	candidates, err := s.repo.GetActiveTeamMembers(author.TeamName, []string{authorID})
+	if err != nil {
+		return nil, err
+	}
+
+	weights, err := s.selectionWeights(authorID, candidates)
+	if err != nil {
+		return nil, err
+	}
+	probs := inclusionProbabilities(weights, reviewersPerPR)
+
+	result := make([]entities.CandidateProbability, len(candidates))
+	for i, c := range candidates {
+		result[i] = entities.CandidateProbability{
+			UserID:      c.ID,
+			Username:    c.Username,
+			Weight:      weights[i],
+			Probability: probs[i],
+		}
+	}
+
+	return result, nil
+}
+
+func (s *Service) GetTeamCapacity(teamName string) ([]entities.MemberCapacity, error) {
+	exists, err := s.repo.TeamExists(teamName)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.New(entities.ErrNotFound)
+	}
+
+	return s.repo.GetTeamCapacity(teamName)
+}
+
+const defaultQueuePageSize = 50
+
+// GetUserReviews returns a page of the user's review queue (OPEN pull
+// requests unless filter.Status says otherwise) and the next page cursor.
+func (s *Service) GetUserReviews(userID string, filter entities.ReviewQueueFilter, cursor string) ([]entities.PullRequestShort, string, error) {
+	_, err := s.repo.GetUser(userID)
+	if err == sql.ErrNoRows {
+		return nil, "", errors.New(entities.ErrNotFound)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch filter.Status {
+	case "":
+		filter.Status = entities.StatusOpen
+	case entities.StatusOpen, entities.StatusMerged, entities.StatusClosed, entities.StatusAll:
+	default:
+		return nil, "", errors.New(entities.ErrBadRequest)
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = defaultQueuePageSize
+	}
+	if filter.Limit > maxPRPageSize {
+		filter.Limit = maxPRPageSize
+	}
+
+	if cursor != "" {
+		assignedAt, id, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, "", errors.New(entities.ErrBadRequest)
+		}
+		filter.AfterAssignedAt = &assignedAt
+		filter.AfterID = id
+	}
+
+	prs, err := s.repo.GetUserReviews(userID, filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	calendars, err := s.workCalendars([]string{userID})
+	if err != nil {
+		return nil, "", err
+	}
+	if calendar := calendars[userID]; calendar != nil {
+		for i := range prs {
+			if prs[i].AssignedAt == nil {
+				continue
+			}
+			// The wall-clock age is measured on the database clock.
+			now := prs[i].AssignedAt.Add(time.Duration(prs[i].AgeSeconds) * time.Second)
+			prs[i].AgeSeconds = int64(calendar.elapsed(*prs[i].AssignedAt, now) / time.Second)
+		}
+	}
+
+	nextCursor := ""
+	if len(prs) == filter.Limit {
+		last := prs[len(prs)-1]
+		if last.AssignedAt != nil {
+			nextCursor = encodeCursor(*last.AssignedAt, last.ID)
+		}
+	}
+
+	return prs, nextCursor, nil
+}
+
+// SubmitReview records the verdict of an assigned reviewer on an open PR.
+func (s *Service) SubmitReview(prID, userID, verdict string) (*entities.PullRequest, error) {
+	if verdict != entities.VerdictApproved && verdict != entities.VerdictChangesRequested {
+		return nil, errors.New(entities.ErrBadRequest)
+	}
+
+	_, user, err := s.loadAssignment(prID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetReviewVerdict(prID, userID, verdict, entities.PREvent{
+		Type:          entities.EventReviewSubmitted,
+		PullRequestID: prID,
+		UserID:        userID,
+		TeamName:      user.TeamName,
+		Actor:         userID,
+		Details:       map[string]string{"verdict": verdict},
+	}); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetPullRequest(prID)
 }
 
+func (s *Service) GetPRHistory(prID string) ([]entities.PREvent, error) {
+	exists, err := s.repo.PRExists(prID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.New(entities.ErrNotFound)
+	}
 
+	return s.repo.GetPRHistory(prID)
+}
+
+const (
+	defaultEventPageSize = 50
+	maxEventPageSize     = 500
+)
+
+// ListEvents returns one page of the audit log and the cursor of the next
+// page, which is empty when there are no more events.
+func (s *Service) ListEvents(filter entities.EventFilter) ([]entities.PREvent, string, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultEventPageSize
+	}
+	if filter.Limit > maxEventPageSize {
+		filter.Limit = maxEventPageSize
+	}
+
+	events, err := s.repo.ListEvents(filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if len(events) == filter.Limit {
+		nextCursor = strconv.FormatInt(events[len(events)-1].ID, 10)
+	}
+
+	return events, nextCursor, nil
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+const reasonSLAOverdue = "sla_overdue"
+
+func (s *Service) GetTeamSLA(teamName string) (*entities.TeamSLA, error) {
+	sla, err := s.repo.GetTeamSLA(teamName)
+	if err == sql.ErrNoRows {
+		return nil, errors.New(entities.ErrNotFound)
+	}
+	return sla, err
+}
+
+// SetTeamSLA configures the review SLA of a team. A nil ReviewSLAHours turns
+// SLA tracking off; ReassignAfterHours, when set, must exceed the SLA.
+func (s *Service) SetTeamSLA(sla *entities.TeamSLA, actor string) (*entities.TeamSLA, error) {
+	if err := validateSLA(sla.ReviewSLAHours, sla.ReassignAfterHours); err != nil {
+		return nil, err
+	}
+
+	details := map[string]string{
+		"review_sla_hours":     hoursOrOff(sla.ReviewSLAHours),
+		"reassign_after_hours": hoursOrOff(sla.ReassignAfterHours),
+	}
+	err := s.repo.SetTeamSLA(sla, entities.PREvent{
+		Type:     entities.EventTeamSLAChanged,
+		TeamName: sla.TeamName,
+		Actor:    actor,
+		Details:  details,
+	})
+	if err == sql.ErrNoRows {
+		return nil, errors.New(entities.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetTeamSLA(sla.TeamName)
+}
+
+// validateSLA requires positive hours and automatic reassignment only after
+// the SLA itself has passed.
+func validateSLA(reviewSLAHours, reassignAfterHours *int) error {
+	if reviewSLAHours != nil && *reviewSLAHours <= 0 {
+		return errors.New(entities.ErrBadRequest)
+	}
+	if reassignAfterHours != nil {
+		if reviewSLAHours == nil || *reassignAfterHours <= *reviewSLAHours {
+			return errors.New(entities.ErrBadRequest)
+		}
+	}
+	return nil
+}
+
+func hoursOrOff(hours *int) string {
+	if hours == nil {
+		return "off"
+	}
+	return strconv.Itoa(*hours)
+}
+
+// slaElapsed is the business time of the reviewer counted against the SLA
+// of an assignment.
+func slaElapsed(a entities.SLAAssignment, calendars map[string]*workCalendar) time.Duration {
+	return calendars[a.UserID].elapsed(a.AssignedAt, a.Now)
+}
+
+func (s *Service) assignmentCalendars(assignments []entities.SLAAssignment) (map[string]*workCalendar, error) {
+	userIDs := make([]string, len(assignments))
+	for i, a := range assignments {
+		userIDs[i] = a.UserID
+	}
+	return s.workCalendars(userIDs)
+}
+
+// GetOverdueReviews lists open assignments that exceeded their team's SLA.
+func (s *Service) GetOverdueReviews(teamName string) ([]entities.OverdueReview, error) {
+	if teamName != "" {
+		exists, err := s.repo.TeamExists(teamName)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, errors.New(entities.ErrNotFound)
+		}
+	}
+
+	assignments, err := s.repo.GetSLATrackedAssignments(teamName)
+	if err != nil {
+		return nil, err
+	}
+	calendars, err := s.assignmentCalendars(assignments)
+	if err != nil {
+		return nil, err
+	}
+
+	overdue := []entities.OverdueReview{}
+	for _, a := range assignments {
+		elapsed := slaElapsed(a, calendars)
+		slaHours := *a.SLA.ReviewSLAHours
+		if elapsed < time.Duration(slaHours)*time.Hour {
+			continue
+		}
+		overdue = append(overdue, entities.OverdueReview{
+			PullRequestID:   a.PullRequestID,
+			PullRequestName: a.PullRequestName,
+			UserID:          a.UserID,
+			TeamName:        a.TeamName,
+			AssignedAt:      a.AssignedAt,
+			ElapsedHours:    elapsed.Hours(),
+			SLAHours:        slaHours,
+			BreachedAt:      a.BreachedAt,
+		})
+	}
+
+	return overdue, nil
+}
+
+// CheckSLA records new SLA breaches and, for teams with a reassignment
+// threshold, hands reviews that exceeded it to another reviewer. It returns
+// the number of breaches and reassignments performed.
+func (s *Service) CheckSLA() (int, error) {
+	assignments, err := s.repo.GetSLATrackedAssignments("")
+	if err != nil {
+		return 0, err
+	}
+	calendars, err := s.assignmentCalendars(assignments)
+	if err != nil {
+		return 0, err
+	}
+
+	actions := 0
+	for _, a := range assignments {
+		elapsed := slaElapsed(a, calendars)
+
+		if a.BreachedAt == nil && elapsed >= time.Duration(*a.SLA.ReviewSLAHours)*time.Hour {
+			err := s.repo.MarkSLABreached(a.PullRequestID, a.UserID, a.Now, entities.PREvent{
+				Type:          entities.EventSLABreached,
+				PullRequestID: a.PullRequestID,
+				UserID:        a.UserID,
+				TeamName:      a.TeamName,
+				Actor:         entities.ActorSystem,
+				Details: map[string]string{
+					"sla_hours":     strconv.Itoa(*a.SLA.ReviewSLAHours),
+					"elapsed_hours": strconv.FormatFloat(elapsed.Hours(), 'f', 1, 64),
+				},
+			})
+			if err != nil && err != sql.ErrNoRows {
+				return actions, err
+			}
+			if err == nil {
+				actions++
+			}
+		}
+
+		if a.SLA.ReassignAfterHours != nil && elapsed >= time.Duration(*a.SLA.ReassignAfterHours)*time.Hour {
+			_, _, err := s.ReassignReviewer(a.PullRequestID, a.UserID, reasonSLAOverdue, entities.ActorSystem)
+			if err != nil {
+				if err.Error() != entities.ErrNoCandidate {
+					log.Printf("Error reassigning overdue review %s from %s: %v", a.PullRequestID, a.UserID, err)
+				}
+				continue
+			}
+			actions++
+		}
+	}
+
+	return actions, nil
+}
+
+func (s *Service) RunSLACheck(ctx context.Context, interval time.Duration) {
+	runPeriodically(ctx, interval, "SLA breaches and overdue reassignments", s.CheckSLA)
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+const reasonTopUp = "top_up"
+
+func (s *Service) GetUnderstaffedPRs(teamName string) ([]entities.UnderstaffedPR, error) {
+	if teamName != "" {
+		exists, err := s.repo.TeamExists(teamName)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, errors.New(entities.ErrNotFound)
+		}
+	}
+
+	return s.repo.GetUnderstaffedPRs(teamName, reviewersPerPR)
+}
+
+// TopUpReviewers assigns missing reviewers to under-staffed OPEN pull requests
+// of teamName (every team when empty). A pull request that cannot be topped
+// up is skipped and reported in the result, so one failure does not hold up
+// the rest.
+func (s *Service) TopUpReviewers(teamName string) (*entities.TopUpResult, error) {
+	prs, err := s.repo.GetUnderstaffedPRs(teamName, reviewersPerPR)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &entities.TopUpResult{Skipped: []entities.TopUpSkip{}}
+	for _, u := range prs {
+		n, err := s.topUpPR(u)
+		result.Assigned += n
+		if err != nil {
+			result.Skipped = append(result.Skipped, entities.TopUpSkip{PullRequestID: u.PullRequestID, Error: err.Error()})
+		}
+	}
+
+	return result, nil
+}
+
+// topUpTeam tops up teamName after a change that may have brought in
+// candidates and logs what could not be filled, for callers that have no
+// response to report it in.
+func (s *Service) topUpTeam(teamName string) *entities.TopUpResult {
+	result, err := s.TopUpReviewers(teamName)
+	if err != nil {
+		log.Printf("Error topping up reviewers of team %s: %v", teamName, err)
+		return nil
+	}
+	for _, skip := range result.Skipped {
+		log.Printf("Error topping up reviewers of PR %s: %s", skip.PullRequestID, skip.Error)
+	}
+	return result
+}
+
+func (s *Service) topUpPR(u entities.UnderstaffedPR) (int, error) {
+	pr, err := s.repo.GetPullRequest(u.PullRequestID)
+	if err != nil {
+		return 0, err
+	}
+
+	excludeIDs := append([]string{pr.AuthorID}, pr.AssignedReviewers...)
+	decliners, err := s.repo.GetPRDecliners(pr.ID)
+	if err != nil {
+		return 0, err
+	}
+	excludeIDs = append(excludeIDs, decliners...)
+
+	candidates, err := s.repo.GetActiveTeamMembers(u.TeamName, excludeIDs)
+	if err != nil || len(candidates) == 0 {
+		return 0, err
+	}
+
+	weights, err := s.selectionWeights(pr.AuthorID, candidates)
+	if err != nil {
+		return 0, err
+	}
+
+	filled := 0
+	for _, reviewer := range s.selectRandomReviewers(candidates, weights, u.Required-len(pr.AssignedReviewers)) {
+		if err := s.repo.AddReviewer(pr.ID, reviewer.ID, entities.PREvent{
+			Type:          entities.EventReviewerAssigned,
+			PullRequestID: pr.ID,
+			NewUserID:     reviewer.ID,
+			TeamName:      u.TeamName,
+			Reason:        reasonTopUp,
+			Actor:         entities.ActorSystem,
+		}); err != nil {
+			return filled, err
+		}
+		filled++
+	}
+
+	return filled, nil
+}
+
+func (s *Service) RunTopUp(ctx context.Context, interval time.Duration) {
+	runPeriodically(ctx, interval, "topped up under-staffed PRs", func() (int, error) {
+		result := s.topUpTeam("")
+		if result == nil {
+			return 0, nil
+		}
+		return result.Assigned, nil
+	})
+}
@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+const reasonStale = "stale"
+
+func (s *Service) GetStalePolicy(teamName string) (*entities.StalePolicy, error) {
+	p, err := s.repo.GetStalePolicy(teamName)
+	if err == sql.ErrNoRows {
+		return nil, errors.New(entities.ErrNotFound)
+	}
+	return p, err
+}
+
+// SetStalePolicy configures staleness handling of a team. Either threshold
+// may be nil to turn that step off; with both set, closing must come after
+// the warning.
+func (s *Service) SetStalePolicy(p *entities.StalePolicy, actor string) (*entities.StalePolicy, error) {
+	if err := validateStalePolicy(p.WarnAfterDays, p.CloseAfterDays); err != nil {
+		return nil, err
+	}
+	p.ExemptLabel = strings.TrimSpace(p.ExemptLabel)
+
+	err := s.repo.SetStalePolicy(p, entities.PREvent{
+		Type:     entities.EventTeamStalePolicyChanged,
+		TeamName: p.TeamName,
+		Actor:    actor,
+		Details: map[string]string{
+			"warn_after_days":  daysOrOff(p.WarnAfterDays),
+			"close_after_days": daysOrOff(p.CloseAfterDays),
+			"exempt_label":     p.ExemptLabel,
+		},
+	})
+	if err == sql.ErrNoRows {
+		return nil, errors.New(entities.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetStalePolicy(p.TeamName)
+}
+
+// validateStalePolicy requires positive thresholds with closing after the
+// warning.
+func validateStalePolicy(warnAfterDays, closeAfterDays *int) error {
+	if warnAfterDays != nil && *warnAfterDays <= 0 {
+		return errors.New(entities.ErrBadRequest)
+	}
+	if closeAfterDays != nil && *closeAfterDays <= 0 {
+		return errors.New(entities.ErrBadRequest)
+	}
+	if warnAfterDays != nil && closeAfterDays != nil && *closeAfterDays <= *warnAfterDays {
+		return errors.New(entities.ErrBadRequest)
+	}
+	return nil
+}
+
+func daysOrOff(days *int) string {
+	if days == nil {
+		return "off"
+	}
+	return strconv.Itoa(*days)
+}
+
+// staleAction decides what the policy does to an idle PR now. When warnings
+// are enabled a PR is closed only after it has been warned since its last
+// activity, so authors always get a chance to react.
+func staleAction(p entities.StalePR) string {
+	idle := p.Now.Sub(p.LastActivityAt)
+	warned := p.WarnedAt != nil && !p.WarnedAt.Before(p.LastActivityAt)
+
+	if p.Policy.CloseAfterDays != nil && idle >= days(*p.Policy.CloseAfterDays) {
+		if p.Policy.WarnAfterDays == nil || warned {
+			return entities.StaleActionClose
+		}
+		return entities.StaleActionWarn
+	}
+	if p.Policy.WarnAfterDays != nil && idle >= days(*p.Policy.WarnAfterDays) && !warned {
+		return entities.StaleActionWarn
+	}
+	return ""
+}
+
+func days(n int) time.Duration {
+	return time.Duration(n) * 24 * time.Hour
+}
+
+// PreviewStalePRs lists the pull requests the next stale check would warn
+// about or close, without changing anything.
+func (s *Service) PreviewStalePRs(teamName string) ([]entities.StalePR, error) {
+	if teamName != "" {
+		exists, err := s.repo.TeamExists(teamName)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, errors.New(entities.ErrNotFound)
+		}
+	}
+
+	candidates, err := s.repo.GetStaleCandidates(teamName)
+	if err != nil {
+		return nil, err
+	}
+
+	affected := []entities.StalePR{}
+	for _, p := range candidates {
+		if p.Action = staleAction(p); p.Action != "" {
+			p.IdleDays = p.Now.Sub(p.LastActivityAt).Hours() / 24
+			affected = append(affected, p)
+		}
+	}
+
+	return affected, nil
+}
+
+// CheckStalePRs warns about and closes idle pull requests according to the
+// stale policies of their authors' teams. It returns the number of PRs acted
+// upon.
+func (s *Service) CheckStalePRs() (int, error) {
+	affected, err := s.PreviewStalePRs("")
+	if err != nil {
+		return 0, err
+	}
+
+	actions := 0
+	for _, p := range affected {
+		idleDays := strconv.FormatFloat(p.IdleDays, 'f', 1, 64)
+		switch p.Action {
+		case entities.StaleActionWarn:
+			err = s.repo.MarkStaleWarned(p.PullRequestID, p.Now, entities.PREvent{
+				Type:          entities.EventPRStaleWarning,
+				PullRequestID: p.PullRequestID,
+				UserID:        p.AuthorID,
+				TeamName:      p.TeamName,
+				Actor:         entities.ActorSystem,
+				Details: map[string]string{
+					"idle_days":        idleDays,
+					"close_after_days": daysOrOff(p.Policy.CloseAfterDays),
+				},
+			})
+		case entities.StaleActionClose:
+			err = s.repo.ClosePullRequest(p.PullRequestID, p.Now, entities.PREvent{
+				Type:          entities.EventPRStatusChanged,
+				PullRequestID: p.PullRequestID,
+				TeamName:      p.TeamName,
+				Reason:        reasonStale,
+				Actor:         entities.ActorSystem,
+				Details: map[string]string{
+					"from":      entities.StatusOpen,
+					"to":        entities.StatusClosed,
+					"idle_days": idleDays,
+				},
+			})
+		}
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return actions, err
+		}
+		actions++
+	}
+
+	return actions, nil
+}
+
+func (s *Service) RunStaleCheck(ctx context.Context, interval time.Duration) {
+	runPeriodically(ctx, interval, "stale pull requests", s.CheckStalePRs)
+}
+
+// SetPRLabels replaces the labels of a pull request. Blank and repeated
+// labels are dropped.
+func (s *Service) SetPRLabels(prID string, labels []string, actor string) (*entities.PullRequest, error) {
+	pr, err := s.repo.GetPullRequest(prID)
+	if err == sql.ErrNoRows {
+		return nil, errors.New(entities.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	cleaned := []string{}
+	for _, label := range labels {
+		label = strings.TrimSpace(label)
+		if label == "" || seen[label] {
+			continue
+		}
+		seen[label] = true
+		cleaned = append(cleaned, label)
+	}
+
+	author, err := s.repo.GetUser(pr.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetPRLabels(prID, cleaned, entities.PREvent{
+		Type:          entities.EventPRLabelsChanged,
+		PullRequestID: prID,
+		TeamName:      author.TeamName,
+		Actor:         actor,
+		Details: map[string]string{
+			"from": strings.Join(pr.Labels, ","),
+			"to":   strings.Join(cleaned, ","),
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetPullRequest(prID)
+}
+
+// ClosePullRequest closes an open pull request without merging it. Closing
+// a closed PR is a no-op.
+func (s *Service) ClosePullRequest(prID, reason, actor string) (*entities.PullRequest, error) {
+	pr, err := s.repo.GetPullRequest(prID)
+	if err == sql.ErrNoRows {
+		return nil, errors.New(entities.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch pr.Status {
+	case entities.StatusClosed:
+		return pr, nil
+	case entities.StatusMerged:
+		return nil, errors.New(entities.ErrPRMerged)
+	}
+
+	author, err := s.repo.GetUser(pr.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.ClosePullRequest(prID, time.Now().UTC(), entities.PREvent{
+		Type:          entities.EventPRStatusChanged,
+		PullRequestID: prID,
+		TeamName:      author.TeamName,
+		Reason:        reason,
+		Actor:         actor,
+		Details: map[string]string{
+			"from": entities.StatusOpen,
+			"to":   entities.StatusClosed,
+		},
+	})
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	return s.repo.GetPullRequest(prID)
+}
+
+// ReopenPullRequest brings a closed pull request back to OPEN with its
+// reviewers. Reopening an open PR is a no-op.
+func (s *Service) ReopenPullRequest(prID, actor string) (*entities.PullRequest, error) {
+	pr, err := s.repo.GetPullRequest(prID)
+	if err == sql.ErrNoRows {
+		return nil, errors.New(entities.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch pr.Status {
+	case entities.StatusOpen:
+		return pr, nil
+	case entities.StatusMerged:
+		return nil, errors.New(entities.ErrPRMerged)
+	}
+
+	author, err := s.repo.GetUser(pr.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.ReopenPullRequest(prID, entities.PREvent{
+		Type:          entities.EventPRStatusChanged,
+		PullRequestID: prID,
+		TeamName:      author.TeamName,
+		Actor:         actor,
+		Details: map[string]string{
+			"from": entities.StatusClosed,
+			"to":   entities.StatusOpen,
+		},
+	})
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	return s.repo.GetPullRequest(prID)
+}
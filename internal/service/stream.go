@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+const (
+	eventStreamBuffer    = 256
+	eventStreamBatchSize = 500
+)
+
+// EventSubscription receives live events matching its filter. Events is
+// closed when the subscriber falls behind or the stream stops; the client is
+// expected to resume from the last event it got.
+type EventSubscription struct {
+	Events <-chan entities.PREvent
+	// Since is the id up to which every event had been published when the
+	// subscription started. Later events arrive through Events in id order.
+	Since int64
+
+	events chan entities.PREvent
+	filter entities.EventFilter
+	hub    *eventHub
+}
+
+func (sub *EventSubscription) Close() {
+	sub.hub.mu.Lock()
+	defer sub.hub.mu.Unlock()
+	sub.hub.drop(sub)
+}
+
+func (sub *EventSubscription) matches(e entities.PREvent) bool {
+	f := sub.filter
+	if !containsString(f.Types, e.Type) {
+		return false
+	}
+	if f.TeamName != "" && e.TeamName != f.TeamName {
+		return false
+	}
+	if f.UserID != "" && e.UserID != f.UserID && e.OldUserID != f.UserID && e.NewUserID != f.UserID {
+		return false
+	}
+	return true
+}
+
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// eventHub polls the event log once for all subscribers. Events up to cursor
+// were published. Event ids become visible in order (see
+// repos.insertEvents), so nothing can show up below the cursor later.
+type eventHub struct {
+	mu      sync.Mutex
+	running bool
+	cursor  int64
+	subs    map[*EventSubscription]bool
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{subs: make(map[*EventSubscription]bool)}
+}
+
+func (h *eventHub) drop(sub *EventSubscription) {
+	if h.subs[sub] {
+		delete(h.subs, sub)
+		close(sub.events)
+	}
+}
+
+// publish hands an event to every matching subscriber. Subscribers with a
+// full buffer are dropped rather than holding up everyone else.
+func (h *eventHub) publish(e entities.PREvent) {
+	for sub := range h.subs {
+		if !sub.matches(e) {
+			continue
+		}
+		select {
+		case sub.events <- e:
+		default:
+			h.drop(sub)
+		}
+	}
+}
+
+// pollEvents publishes the events committed since the last poll. Only the
+// poller moves the cursor, so the log is read without holding the lock.
+func (s *Service) pollEvents() (int, error) {
+	h := s.eventHub
+	h.mu.Lock()
+	cursor := h.cursor
+	h.mu.Unlock()
+
+	events, err := s.repo.ListEvents(entities.EventFilter{AfterID: cursor, Limit: eventStreamBatchSize})
+	if err != nil {
+		return 0, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	published := 0
+	for _, e := range events {
+		if containsString(entities.StreamEventTypes, e.Type) {
+			h.publish(e)
+			published++
+		}
+		h.cursor = e.ID
+	}
+
+	return published, nil
+}
+
+// SubscribeEvents registers a live subscriber. Only filter.Types, TeamName
+// and UserID are used; Types defaults to entities.StreamEventTypes.
+func (s *Service) SubscribeEvents(filter entities.EventFilter) (*EventSubscription, error) {
+	if len(filter.Types) == 0 {
+		filter.Types = entities.StreamEventTypes
+	}
+
+	h := s.eventHub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.running {
+		return nil, &entities.DomainError{Code: entities.ErrUnavailable, Detail: "event stream is not running"}
+	}
+
+	events := make(chan entities.PREvent, eventStreamBuffer)
+	sub := &EventSubscription{Events: events, Since: h.cursor, events: events, filter: filter, hub: h}
+	h.subs[sub] = true
+	return sub, nil
+}
+
+// RunEventStream feeds the live event stream, starting from the end of the
+// log, until ctx is cancelled and then closes every subscription.
+func (s *Service) RunEventStream(ctx context.Context, interval time.Duration) {
+	h := s.eventHub
+	for {
+		latest, err := s.repo.LatestEventID()
+		if err == nil {
+			h.mu.Lock()
+			h.cursor = latest
+			h.running = true
+			h.mu.Unlock()
+			break
+		}
+		log.Printf("Error starting event stream: %v", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+
+	runPeriodically(ctx, interval, "event stream", s.pollEvents)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.running = false
+	for sub := range h.subs {
+		h.drop(sub)
+	}
+}
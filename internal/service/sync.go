@@ -0,0 +1,495 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+	"github.com/alexalexbor04/pull_request_service/internal/repos"
+)
+
+const reasonTeamSync = "team_sync"
+
+// syncAttempts bounds how often SyncOrg plans again after teams or users
+// changed under it.
+const syncAttempts = 3
+
+// orgSyncPlan is a sync computed against a snapshot of teams and users.
+type orgSyncPlan struct {
+	result   *entities.OrgSyncResult
+	teams    []entities.TeamSettings
+	users    []entities.User
+	newTeams []string
+	settings []entities.TeamSettings
+	writes   []entities.User
+	releases []entities.ReviewAssignment
+	events   []entities.PREvent
+	topUp    map[string]bool
+}
+
+// SyncOrg brings teams and users to the desired state. The plan is computed
+// against the database and, unless dryRun is set, applied in one
+// transaction; if teams or users change in between, the sync is planned
+// again. Active users missing from the state are deactivated; teams are
+// never deleted. Deactivated users give up their undecided open reviews and
+// moved users those of their former teams; the pull requests are then topped
+// up from their authors' teams.
+func (s *Service) SyncOrg(state *entities.OrgState, dryRun bool, actor string) (*entities.OrgSyncResult, error) {
+	if err := validateOrgState(state); err != nil {
+		return nil, err
+	}
+
+	var plan *orgSyncPlan
+	for attempt := 1; ; attempt++ {
+		var err error
+		plan, err = s.planOrgSync(state, dryRun, actor)
+		if err != nil {
+			return nil, err
+		}
+		if dryRun || len(plan.result.Changes) == 0 {
+			return plan.result, nil
+		}
+
+		err = s.repo.ApplyOrgSync(plan.teams, plan.users, plan.newTeams, plan.settings,
+			plan.writes, plan.releases, plan.events)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, repos.ErrOrgChanged) || attempt == syncAttempts {
+			return nil, err
+		}
+	}
+
+	teamNames := make([]string, 0, len(plan.topUp))
+	for name := range plan.topUp {
+		teamNames = append(teamNames, name)
+	}
+	sort.Strings(teamNames)
+	for _, name := range teamNames {
+		topUp, err := s.TopUpReviewers(name)
+		if err != nil {
+			log.Printf("Error topping up reviewers of team %s: %v", name, err)
+			continue
+		}
+		plan.result.TopUpSkipped = append(plan.result.TopUpSkipped, topUp.Skipped...)
+	}
+
+	return plan.result, nil
+}
+
+// planOrgSync computes the changes that bring the current teams and users to
+// state.
+func (s *Service) planOrgSync(state *entities.OrgState, dryRun bool, actor string) (*orgSyncPlan, error) {
+	teams, err := s.repo.ListTeamSettings()
+	if err != nil {
+		return nil, err
+	}
+	currentTeams := make(map[string]entities.TeamSettings, len(teams))
+	for _, t := range teams {
+		currentTeams[t.TeamName] = t
+	}
+
+	users, err := s.repo.ListAllUsers()
+	if err != nil {
+		return nil, err
+	}
+	currentUsers := make(map[string]entities.User, len(users))
+	for _, u := range users {
+		currentUsers[u.ID] = u
+	}
+
+	result := &entities.OrgSyncResult{DryRun: dryRun, Changes: []entities.OrgSyncChange{}}
+	var (
+		newTeams []string
+		settings []entities.TeamSettings
+		writes   []entities.User
+		events   []entities.PREvent
+	)
+	listed := make(map[string]bool)
+	topUp := make(map[string]bool)
+
+	for _, team := range state.Teams {
+		current, exists := currentTeams[team.TeamName]
+		if !exists {
+			current = entities.TeamSettings{TeamName: team.TeamName}
+			newTeams = append(newTeams, team.TeamName)
+			events = append(events, entities.PREvent{
+				Type:     entities.EventTeamCreated,
+				TeamName: team.TeamName,
+				Actor:    actor,
+			})
+		}
+
+		var changes map[string]entities.FieldChange
+		if team.Settings != nil {
+			target := *team.Settings
+			target.TeamName = team.TeamName
+			target.StaleExemptLabel = strings.TrimSpace(target.StaleExemptLabel)
+
+			changes = settingsChanges(current, target)
+			if len(changes) > 0 {
+				settings = append(settings, target)
+				events = append(events, settingsEvents(changes, target, actor)...)
+			}
+		}
+
+		switch {
+		case !exists:
+			result.Changes = append(result.Changes, entities.OrgSyncChange{
+				Action:   entities.SyncActionCreateTeam,
+				TeamName: team.TeamName,
+				Changes:  changes,
+			})
+		case len(changes) > 0:
+			result.Changes = append(result.Changes, entities.OrgSyncChange{
+				Action:   entities.SyncActionUpdateTeam,
+				TeamName: team.TeamName,
+				Changes:  changes,
+			})
+		}
+
+		for _, m := range team.Members {
+			listed[m.UserID] = true
+
+			target := entities.User{
+				ID:           m.UserID,
+				Username:     m.Username,
+				TeamName:     team.TeamName,
+				IsActive:     m.IsActive == nil || *m.IsActive,
+				ReviewWeight: 1.0,
+			}
+			current, exists := currentUsers[m.UserID]
+			if exists {
+				target.MaxOpenReviews = current.MaxOpenReviews
+				target.ReviewWeight = current.ReviewWeight
+			}
+			if m.MaxOpenReviews != nil {
+				target.MaxOpenReviews = m.MaxOpenReviews
+			}
+			if m.ReviewWeight != nil {
+				target.ReviewWeight = *m.ReviewWeight
+			}
+
+			var prev *entities.User
+			if exists {
+				prev = &current
+			}
+			changes := userChanges(prev, target)
+			if len(changes) == 0 {
+				result.Unchanged++
+				continue
+			}
+
+			action := entities.SyncActionUpdateUser
+			switch {
+			case !exists:
+				action = entities.SyncActionCreateUser
+			case current.TeamName != target.TeamName:
+				action = entities.SyncActionMoveUser
+			}
+			result.Changes = append(result.Changes, entities.OrgSyncChange{
+				Action:   action,
+				TeamName: target.TeamName,
+				UserID:   target.ID,
+				Changes:  changes,
+			})
+			writes = append(writes, target)
+			events = append(events, userEvents(changes, target, actor)...)
+
+			if target.IsActive && (!exists || !current.IsActive || current.TeamName != target.TeamName) {
+				topUp[target.TeamName] = true
+			}
+		}
+	}
+
+	for _, u := range users {
+		if listed[u.ID] || !u.IsActive {
+			continue
+		}
+		target := u
+		target.IsActive = false
+		changes := userChanges(&u, target)
+
+		result.Changes = append(result.Changes, entities.OrgSyncChange{
+			Action:   entities.SyncActionDeactivateUser,
+			TeamName: u.TeamName,
+			UserID:   u.ID,
+			Changes:  changes,
+		})
+		writes = append(writes, target)
+		events = append(events, userEvents(changes, target, actor)...)
+	}
+
+	released, err := s.releasedReviews(writes, currentUsers)
+	if err != nil {
+		return nil, err
+	}
+	var releases []entities.ReviewAssignment
+	for _, change := range released {
+		result.Changes = append(result.Changes, change)
+		releases = append(releases, entities.ReviewAssignment{PullRequestID: change.PullRequestID, UserID: change.UserID})
+		events = append(events, entities.PREvent{
+			Type:          entities.EventReviewerRemoved,
+			PullRequestID: change.PullRequestID,
+			OldUserID:     change.UserID,
+			TeamName:      change.TeamName,
+			Reason:        reasonTeamSync,
+			Actor:         actor,
+		})
+		topUp[change.TeamName] = true
+	}
+
+	return &orgSyncPlan{
+		result:   result,
+		teams:    teams,
+		users:    users,
+		newTeams: newTeams,
+		settings: settings,
+		writes:   writes,
+		releases: releases,
+		events:   events,
+		topUp:    topUp,
+	}, nil
+}
+
+// releasedReviews plans the undecided open reviews that users written by a
+// sync give up: all of a user being deactivated and, of a user moving to
+// another team, those of pull requests authored outside the new team. Each
+// change carries the team of the pull request's author after the sync.
+func (s *Service) releasedReviews(writes []entities.User, current map[string]entities.User) ([]entities.OrgSyncChange, error) {
+	after := make(map[string]entities.User, len(current))
+	for id, u := range current {
+		after[id] = u
+	}
+	var userIDs []string
+	for _, target := range writes {
+		after[target.ID] = target
+		u, exists := current[target.ID]
+		if exists && (u.TeamName != target.TeamName || u.IsActive && !target.IsActive) {
+			userIDs = append(userIDs, target.ID)
+		}
+	}
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	assignments, err := s.repo.GetAssignmentsForUsers(userIDs, entities.StatusOpen)
+	if err != nil {
+		return nil, err
+	}
+	var prIDs []string
+	for _, a := range assignments {
+		prIDs = append(prIDs, a.PullRequestID)
+	}
+	prs, err := s.repo.GetPullRequestsByIDs(prIDs)
+	if err != nil {
+		return nil, err
+	}
+	authors := make(map[string]string, len(prs))
+	for _, pr := range prs {
+		authors[pr.ID] = pr.AuthorID
+	}
+
+	var released []entities.OrgSyncChange
+	for _, a := range assignments {
+		if a.Verdict != "" {
+			continue
+		}
+		reviewer := after[a.UserID]
+		teamName := after[authors[a.PullRequestID]].TeamName
+		if !reviewer.IsActive || teamName != reviewer.TeamName {
+			released = append(released, entities.OrgSyncChange{
+				Action:        entities.SyncActionReleaseReview,
+				TeamName:      teamName,
+				UserID:        a.UserID,
+				PullRequestID: a.PullRequestID,
+			})
+		}
+	}
+	return released, nil
+}
+
+func validateOrgState(state *entities.OrgState) error {
+	badRequest := func(format string, args ...interface{}) error {
+		return &entities.DomainError{Code: entities.ErrBadRequest, Detail: fmt.Sprintf(format, args...)}
+	}
+
+	teams := make(map[string]bool)
+	users := make(map[string]string)
+	for i, team := range state.Teams {
+		if team.TeamName == "" {
+			return badRequest("team %d has no team_name", i+1)
+		}
+		if teams[team.TeamName] {
+			return badRequest("team %s is listed twice", team.TeamName)
+		}
+		teams[team.TeamName] = true
+
+		if st := team.Settings; st != nil {
+			if validateSLA(st.ReviewSLAHours, st.ReassignAfterHours) != nil {
+				return badRequest("team %s: review_sla_hours must be positive and reassign_after_hours greater than it", team.TeamName)
+			}
+			if validateStalePolicy(st.StaleWarnAfterDays, st.StaleCloseAfterDays) != nil {
+				return badRequest("team %s: stale days must be positive and stale_close_after_days greater than stale_warn_after_days", team.TeamName)
+			}
+		}
+
+		for j, m := range team.Members {
+			if m.UserID == "" || m.Username == "" {
+				return badRequest("team %s: member %d needs user_id and username", team.TeamName, j+1)
+			}
+			if other, ok := users[m.UserID]; ok {
+				return badRequest("user %s is listed in teams %s and %s", m.UserID, other, team.TeamName)
+			}
+			users[m.UserID] = team.TeamName
+
+			if m.MaxOpenReviews != nil && *m.MaxOpenReviews < 0 {
+				return badRequest("user %s: max_open_reviews must not be negative", m.UserID)
+			}
+			if m.ReviewWeight != nil && *m.ReviewWeight <= 0 {
+				return badRequest("user %s: review_weight must be positive", m.UserID)
+			}
+		}
+	}
+	return nil
+}
+
+// settingsChanges lists the settings that differ, keyed by their JSON names.
+func settingsChanges(current, target entities.TeamSettings) map[string]entities.FieldChange {
+	changes := make(map[string]entities.FieldChange)
+	add := func(field, from, to string) {
+		if from != to {
+			changes[field] = entities.FieldChange{From: from, To: to}
+		}
+	}
+	add("review_sla_hours", hoursOrOff(current.ReviewSLAHours), hoursOrOff(target.ReviewSLAHours))
+	add("reassign_after_hours", hoursOrOff(current.ReassignAfterHours), hoursOrOff(target.ReassignAfterHours))
+	add("stale_warn_after_days", daysOrOff(current.StaleWarnAfterDays), daysOrOff(target.StaleWarnAfterDays))
+	add("stale_close_after_days", daysOrOff(current.StaleCloseAfterDays), daysOrOff(target.StaleCloseAfterDays))
+	add("stale_exempt_label", current.StaleExemptLabel, target.StaleExemptLabel)
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
+
+// settingsEvents records the changed settings with the same events as the
+// single-purpose endpoints.
+func settingsEvents(changes map[string]entities.FieldChange, target entities.TeamSettings, actor string) []entities.PREvent {
+	var events []entities.PREvent
+	_, sla := changes["review_sla_hours"]
+	_, reassign := changes["reassign_after_hours"]
+	if sla || reassign {
+		events = append(events, entities.PREvent{
+			Type:     entities.EventTeamSLAChanged,
+			TeamName: target.TeamName,
+			Actor:    actor,
+			Details: map[string]string{
+				"review_sla_hours":     hoursOrOff(target.ReviewSLAHours),
+				"reassign_after_hours": hoursOrOff(target.ReassignAfterHours),
+			},
+		})
+	}
+
+	_, warn := changes["stale_warn_after_days"]
+	_, closeAfter := changes["stale_close_after_days"]
+	_, label := changes["stale_exempt_label"]
+	if warn || closeAfter || label {
+		events = append(events, entities.PREvent{
+			Type:     entities.EventTeamStalePolicyChanged,
+			TeamName: target.TeamName,
+			Actor:    actor,
+			Details: map[string]string{
+				"warn_after_days":  daysOrOff(target.StaleWarnAfterDays),
+				"close_after_days": daysOrOff(target.StaleCloseAfterDays),
+				"exempt_label":     target.StaleExemptLabel,
+			},
+		})
+	}
+	return events
+}
+
+// userChanges lists the fields of target that differ from current, or all of
+// them for a new user.
+func userChanges(current *entities.User, target entities.User) map[string]entities.FieldChange {
+	var from entities.User
+	isNew := current == nil
+	if !isNew {
+		from = *current
+	}
+
+	changes := make(map[string]entities.FieldChange)
+	add := func(field, before, after string) {
+		if isNew {
+			before = ""
+		}
+		if isNew || before != after {
+			changes[field] = entities.FieldChange{From: before, To: after}
+		}
+	}
+	add("username", from.Username, target.Username)
+	add("team_name", from.TeamName, target.TeamName)
+	add("is_active", strconv.FormatBool(from.IsActive), strconv.FormatBool(target.IsActive))
+	add("max_open_reviews", limitOrUnlimited(from.MaxOpenReviews), limitOrUnlimited(target.MaxOpenReviews))
+	add("review_weight", formatWeight(from.ReviewWeight), formatWeight(target.ReviewWeight))
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
+
+// userEvents records a user change with the events the single-purpose
+// endpoints emit for the same fields.
+func userEvents(changes map[string]entities.FieldChange, target entities.User, actor string) []entities.PREvent {
+	var events []entities.PREvent
+	base := entities.PREvent{UserID: target.ID, TeamName: target.TeamName, Actor: actor}
+
+	_, username := changes["username"]
+	team, moved := changes["team_name"]
+	if username || moved {
+		e := base
+		e.Type = entities.EventUserUpserted
+		e.Details = map[string]string{
+			"username":  target.Username,
+			"is_active": strconv.FormatBool(target.IsActive),
+		}
+		if moved && team.From != "" {
+			e.Details["from_team"] = team.From
+		}
+		events = append(events, e)
+	}
+	if c, ok := changes["is_active"]; ok && c.From != "" {
+		e := base
+		e.Type = entities.EventUserActiveChanged
+		e.Details = map[string]string{"from": c.From, "to": c.To}
+		events = append(events, e)
+	}
+	if c, ok := changes["max_open_reviews"]; ok && (c.From != "" || target.MaxOpenReviews != nil) {
+		e := base
+		e.Type = entities.EventUserCapacityChanged
+		e.Details = map[string]string{"max_open_reviews": c.To}
+		events = append(events, e)
+	}
+	if c, ok := changes["review_weight"]; ok && (c.From != "" || target.ReviewWeight != 1.0) {
+		e := base
+		e.Type = entities.EventUserWeightChanged
+		e.Details = map[string]string{"review_weight": c.To}
+		events = append(events, e)
+	}
+	return events
+}
+
+func limitOrUnlimited(limit *int) string {
+	if limit == nil {
+		return "unlimited"
+	}
+	return strconv.Itoa(*limit)
+}
+
+func formatWeight(weight float64) string {
+	return strconv.FormatFloat(weight, 'f', -1, 64)
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+	"github.com/alexalexbor04/pull_request_service/internal/repos"
+	"github.com/alexalexbor04/pull_request_service/internal/testdb"
+)
+
+// The database is shared with other tests, so only the dry run is exercised
+// here: applying a state would deactivate everyone it does not list. See
+// TestSyncOrgApply for an applied sync.
+func TestSyncOrgPlansReleasedReviews(t *testing.T) {
+	s, _, suffix := newTestService(t)
+
+	team := "sync-" + suffix
+	other := "sync-other-" + suffix
+	author := "author-" + suffix
+	leaving := "r1-" + suffix
+	moving := "r2-" + suffix
+	createTestTeam(t, s, team, author, leaving, moving)
+	createTestTeam(t, s, other, "o1-"+suffix)
+
+	pr, err := s.CreatePullRequest("pr-"+suffix, "Add search", author)
+	if err != nil {
+		t.Fatalf("CreatePullRequest: %v", err)
+	}
+	if len(pr.AssignedReviewers) != 2 {
+		t.Fatalf("assigned reviewers = %v, want both team members", pr.AssignedReviewers)
+	}
+
+	state := &entities.OrgState{Teams: []entities.OrgTeam{
+		{TeamName: team, Members: []entities.OrgMember{{UserID: author, Username: author}}},
+		{TeamName: other, Members: []entities.OrgMember{
+			{UserID: "o1-" + suffix, Username: "o1-" + suffix},
+			{UserID: moving, Username: moving},
+		}},
+	}}
+	result, err := s.SyncOrg(state, true, "test")
+	if err != nil {
+		t.Fatalf("SyncOrg: %v", err)
+	}
+
+	var released []string
+	for _, c := range result.Changes {
+		if c.Action != entities.SyncActionReleaseReview || c.PullRequestID != pr.ID {
+			continue
+		}
+		if c.TeamName != team {
+			t.Errorf("release of %s names team %q, want the author's %q", c.UserID, c.TeamName, team)
+		}
+		released = append(released, c.UserID)
+	}
+	sort.Strings(released)
+	if len(released) != 2 || released[0] != leaving || released[1] != moving {
+		t.Fatalf("released reviewers of %s = %v, want [%s %s]", pr.ID, released, leaving, moving)
+	}
+
+	current, err := s.GetPullRequest(pr.ID)
+	if err != nil {
+		t.Fatalf("GetPullRequest: %v", err)
+	}
+	if len(current.AssignedReviewers) != 2 {
+		t.Fatalf("reviewers after a dry run = %v, want them kept", current.AssignedReviewers)
+	}
+}
+
+func TestSyncOrgApply(t *testing.T) {
+	s := New(repos.New(testdb.OpenIsolated(t)))
+
+	createTestTeam(t, s, "backend", "author", "leaving", "moving")
+	createTestTeam(t, s, "frontend", "f1")
+
+	pr, err := s.CreatePullRequest("pr-1", "Add search", "author")
+	if err != nil {
+		t.Fatalf("CreatePullRequest: %v", err)
+	}
+	if len(pr.AssignedReviewers) != 2 {
+		t.Fatalf("assigned reviewers = %v, want both team members", pr.AssignedReviewers)
+	}
+
+	state := &entities.OrgState{Teams: []entities.OrgTeam{
+		{TeamName: "backend", Members: []entities.OrgMember{
+			{UserID: "author", Username: "author"},
+			{UserID: "joining", Username: "joining"},
+		}},
+		{TeamName: "frontend", Members: []entities.OrgMember{
+			{UserID: "f1", Username: "f1"},
+			{UserID: "moving", Username: "moving"},
+		}},
+		{TeamName: "mobile", Members: []entities.OrgMember{{UserID: "m1", Username: "m1"}}},
+	}}
+	result, err := s.SyncOrg(state, false, "test")
+	if err != nil {
+		t.Fatalf("SyncOrg: %v", err)
+	}
+
+	actions := make(map[string]string)
+	for _, c := range result.Changes {
+		key := c.UserID
+		switch {
+		case c.Action == entities.SyncActionCreateTeam:
+			key = c.TeamName
+		case c.Action == entities.SyncActionReleaseReview:
+			key = c.UserID + "@" + c.PullRequestID
+		}
+		actions[key] = c.Action
+	}
+	want := map[string]string{
+		"mobile":       entities.SyncActionCreateTeam,
+		"joining":      entities.SyncActionCreateUser,
+		"m1":           entities.SyncActionCreateUser,
+		"moving":       entities.SyncActionMoveUser,
+		"leaving":      entities.SyncActionDeactivateUser,
+		"leaving@pr-1": entities.SyncActionReleaseReview,
+		"moving@pr-1":  entities.SyncActionReleaseReview,
+	}
+	for key, action := range want {
+		if actions[key] != action {
+			t.Errorf("change of %s = %q, want %q", key, actions[key], action)
+		}
+	}
+	if len(actions) != len(want) {
+		t.Errorf("changes = %+v, want %d", result.Changes, len(want))
+	}
+
+	users := map[string]struct {
+		team   string
+		active bool
+	}{
+		"joining": {"backend", true},
+		"moving":  {"frontend", true},
+		"leaving": {"backend", false},
+		"m1":      {"mobile", true},
+	}
+	for id, want := range users {
+		user, err := s.GetUser(id)
+		if err != nil {
+			t.Fatalf("GetUser %s: %v", id, err)
+		}
+		if user.TeamName != want.team || user.IsActive != want.active {
+			t.Errorf("user %s = %s, active %v; want %s, active %v", id, user.TeamName, user.IsActive, want.team, want.active)
+		}
+	}
+
+	current, err := s.GetPullRequest(pr.ID)
+	if err != nil {
+		t.Fatalf("GetPullRequest: %v", err)
+	}
+	if len(current.AssignedReviewers) != 1 || current.AssignedReviewers[0] != "joining" {
+		t.Fatalf("reviewers after the sync = %v, want the released ones replaced by joining", current.AssignedReviewers)
+	}
+
+	history, err := s.GetPRHistory(pr.ID)
+	if err != nil {
+		t.Fatalf("GetPRHistory: %v", err)
+	}
+	var removed []string
+	for _, e := range history {
+		if e.Type == entities.EventReviewerRemoved {
+			if e.Reason != reasonTeamSync {
+				t.Errorf("removal of %s has reason %q, want %q", e.OldUserID, e.Reason, reasonTeamSync)
+			}
+			removed = append(removed, e.OldUserID)
+		}
+	}
+	sort.Strings(removed)
+	if len(removed) != 2 || removed[0] != "leaving" || removed[1] != "moving" {
+		t.Fatalf("removed reviewers in history = %v, want [leaving moving]", removed)
+	}
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"net/mail"
+	"strings"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+const reasonUserRemoved = "user_removed"
+
+const (
+	defaultUserPageSize = 100
+	maxUserPageSize     = 500
+)
+
+func (s *Service) GetUser(userID string) (*entities.User, error) {
+	user, err := s.repo.GetUser(userID)
+	if err == sql.ErrNoRows {
+		return nil, errors.New(entities.ErrNotFound)
+	}
+	return user, err
+}
+
+// ListUsers returns a page of users and the cursor of the next page.
+func (s *Service) ListUsers(filter entities.UserFilter) ([]entities.User, string, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultUserPageSize
+	}
+	if filter.Limit > maxUserPageSize {
+		filter.Limit = maxUserPageSize
+	}
+
+	users, err := s.repo.ListUsers(filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if len(users) == filter.Limit {
+		nextCursor = users[len(users)-1].ID
+	}
+
+	return users, nextCursor, nil
+}
+
+// UpdateUser applies the non-nil fields of update to the user profile.
+func (s *Service) UpdateUser(userID string, update entities.UserUpdate, actor string) (*entities.User, error) {
+	user, err := s.repo.GetUser(userID)
+	if err == sql.ErrNoRows {
+		return nil, errors.New(entities.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if update.Username != nil {
+		username := strings.TrimSpace(*update.Username)
+		if username == "" {
+			return nil, errors.New(entities.ErrBadRequest)
+		}
+		if err := s.repo.UpdateUsername(userID, username, entities.PREvent{
+			Type:     entities.EventUserUpdated,
+			UserID:   userID,
+			TeamName: user.TeamName,
+			Actor:    actor,
+			Details: map[string]string{
+				"field": "username",
+				"from":  user.Username,
+				"to":    username,
+			},
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if update.Email != nil {
+		email := strings.TrimSpace(*update.Email)
+		if email != "" {
+			addr, err := mail.ParseAddress(email)
+			if err != nil || addr.Address != email {
+				return nil, &entities.DomainError{Code: entities.ErrBadRequest, Detail: "email must be a plain address like jane@example.com"}
+			}
+		}
+		if err := s.repo.UpdateEmail(userID, email, entities.PREvent{
+			Type:     entities.EventUserUpdated,
+			UserID:   userID,
+			TeamName: user.TeamName,
+			Actor:    actor,
+			// Addresses are personal data and stay out of the audit log.
+			Details: map[string]string{"field": "email"},
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.repo.GetUser(userID)
+}
+
+// DeleteUser removes a user. With anonymize the row is kept, so authored pull
+// requests and review history remain intact; otherwise the user is deleted,
+// which is only possible when they authored no pull requests and reviewed
+// none that are merged or closed. Open reviews of the user are dropped and
+// refilled from their team in both modes.
+func (s *Service) DeleteUser(userID string, anonymize bool, actor string) error {
+	user, err := s.repo.GetUser(userID)
+	if err == sql.ErrNoRows {
+		return errors.New(entities.ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !anonymize {
+		authored, err := s.repo.CountAuthoredPRs(userID)
+		if err != nil {
+			return err
+		}
+		if authored > 0 {
+			return &entities.DomainError{
+				Code:   entities.ErrUserReferenced,
+				Detail: "user authored pull requests; anonymize instead",
+			}
+		}
+		reviewed, err := s.repo.CountReviewHistory(userID)
+		if err != nil {
+			return err
+		}
+		if reviewed > 0 {
+			return &entities.DomainError{
+				Code:   entities.ErrUserReferenced,
+				Detail: "user reviewed merged or closed pull requests; anonymize instead",
+			}
+		}
+	}
+
+	events, err := s.openReviewRemovals(user, actor)
+	if err != nil {
+		return err
+	}
+	if anonymize {
+		events = append(events, entities.PREvent{
+			Type:     entities.EventUserAnonymized,
+			UserID:   userID,
+			TeamName: user.TeamName,
+			Actor:    actor,
+		})
+		err = s.repo.AnonymizeUser(userID, events...)
+	} else {
+		events = append(events, entities.PREvent{
+			Type:     entities.EventUserDeleted,
+			UserID:   userID,
+			TeamName: user.TeamName,
+			Actor:    actor,
+		})
+		err = s.repo.DeleteUser(userID, events...)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.topUpTeam(user.TeamName)
+
+	return nil
+}
+
+// openReviewRemovals records the open review assignments a removed user gives
+// up, the same way RemoveReviewer does.
+func (s *Service) openReviewRemovals(user *entities.User, actor string) ([]entities.PREvent, error) {
+	assignments, err := s.repo.GetAssignmentsForUsers([]string{user.ID}, entities.StatusOpen)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []entities.PREvent
+	for _, a := range assignments {
+		events = append(events, entities.PREvent{
+			Type:          entities.EventReviewerRemoved,
+			PullRequestID: a.PullRequestID,
+			OldUserID:     user.ID,
+			TeamName:      user.TeamName,
+			Reason:        reasonUserRemoved,
+			Actor:         actor,
+		})
+	}
+	return events, nil
+}
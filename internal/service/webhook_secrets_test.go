@@ -0,0 +1,39 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWebhookSecretSealing(t *testing.T) {
+	s := New(nil)
+	if _, err := s.sealSecret("s3cr3t"); err == nil {
+		t.Fatal("sealSecret without a key succeeded")
+	}
+
+	s.SetWebhookSecretKey("test key")
+	sealed, err := s.sealSecret("s3cr3t")
+	if err != nil {
+		t.Fatalf("sealSecret: %v", err)
+	}
+	if !strings.HasPrefix(sealed, sealedSecretPrefix) || strings.Contains(sealed, "s3cr3t") {
+		t.Fatalf("sealed secret = %q, want it encrypted", sealed)
+	}
+	if again, _ := s.sealSecret("s3cr3t"); again == sealed {
+		t.Error("sealing twice gave the same ciphertext, want a fresh nonce")
+	}
+
+	secret, err := s.openSecret(sealed)
+	if err != nil || secret != "s3cr3t" {
+		t.Fatalf("openSecret = %q, %v; want s3cr3t", secret, err)
+	}
+	if secret, err := s.openSecret("legacy"); err != nil || secret != "legacy" {
+		t.Errorf("openSecret of an unencrypted secret = %q, %v; want it as is", secret, err)
+	}
+
+	other := New(nil)
+	other.SetWebhookSecretKey("another key")
+	if _, err := other.openSecret(sealed); err == nil {
+		t.Error("openSecret with another key succeeded")
+	}
+}
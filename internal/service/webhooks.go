@@ -0,0 +1,282 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+const (
+	webhookBatchSize   = 50
+	webhookLease       = time.Minute
+	webhookMaxAttempts = 8
+	webhookBaseBackoff = 30 * time.Second
+
+	defaultDeliveryPageSize = 100
+	maxDeliveryPageSize     = 500
+)
+
+// WebhookPayload is the JSON body posted to subscribers.
+type WebhookPayload struct {
+	DeliveryID int64            `json:"delivery_id"`
+	Event      entities.PREvent `json:"event"`
+}
+
+// SignWebhook returns the value of the X-Webhook-Signature-256 header for body.
+func SignWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff is the delay before retrying after the given number of
+// failed attempts: 30s, 1m, 2m, 4m and so on.
+func webhookBackoff(attempts int) time.Duration {
+	return webhookBaseBackoff << (attempts - 1)
+}
+
+func validEventType(eventType string) bool {
+	for _, t := range entities.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateWebhookSubscription registers a URL for events of the given types
+// (all types when empty), optionally only those of one team. Without a secret
+// a random one is generated. The secret is stored encrypted and returned
+// only here.
+func (s *Service) CreateWebhookSubscription(sub *entities.WebhookSubscription) (*entities.WebhookSubscription, error) {
+	if s.webhookKey == nil {
+		return nil, &entities.DomainError{Code: entities.ErrUnavailable, Detail: "webhook subscriptions need WEBHOOK_SECRET_KEY"}
+	}
+	u, err := url.Parse(sub.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, &entities.DomainError{Code: entities.ErrBadRequest, Detail: "url must be an absolute http(s) URL"}
+	}
+	if sub.EventTypes == nil {
+		sub.EventTypes = []string{}
+	}
+	for _, t := range sub.EventTypes {
+		if !validEventType(t) {
+			return nil, &entities.DomainError{Code: entities.ErrBadRequest, Detail: "unknown event type " + t}
+		}
+	}
+	if sub.TeamName != "" {
+		exists, err := s.repo.TeamExists(sub.TeamName)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, errors.New(entities.ErrNotFound)
+		}
+	}
+
+	secret := sub.Secret
+	if secret == "" {
+		if secret, err = newWebhookSecret(); err != nil {
+			return nil, err
+		}
+	}
+	if sub.Secret, err = s.sealSecret(secret); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateWebhookSubscription(sub); err != nil {
+		return nil, err
+	}
+
+	sub.Secret = secret
+	return sub, nil
+}
+
+// ListWebhookSubscriptions returns all subscriptions without their secrets.
+func (s *Service) ListWebhookSubscriptions() ([]entities.WebhookSubscription, error) {
+	subs, err := s.repo.ListWebhookSubscriptions()
+	if err != nil {
+		return nil, err
+	}
+	for i := range subs {
+		subs[i].Secret = ""
+	}
+	return subs, nil
+}
+
+func (s *Service) DeleteWebhookSubscription(id int64) error {
+	err := s.repo.DeleteWebhookSubscription(id)
+	if err == sql.ErrNoRows {
+		return errors.New(entities.ErrNotFound)
+	}
+	return err
+}
+
+// ListWebhookDeliveries returns one page of deliveries and the cursor of the
+// next page, which is empty when there are no more deliveries.
+func (s *Service) ListWebhookDeliveries(filter entities.WebhookDeliveryFilter) ([]entities.WebhookDelivery, string, error) {
+	switch filter.Status {
+	case "", entities.DeliveryPending, entities.DeliveryDelivered, entities.DeliveryDead:
+	default:
+		return nil, "", errors.New(entities.ErrBadRequest)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultDeliveryPageSize
+	}
+	if filter.Limit > maxDeliveryPageSize {
+		filter.Limit = maxDeliveryPageSize
+	}
+
+	deliveries, err := s.repo.ListWebhookDeliveries(filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if len(deliveries) == filter.Limit {
+		nextCursor = strconv.FormatInt(deliveries[len(deliveries)-1].ID, 10)
+	}
+
+	return deliveries, nextCursor, nil
+}
+
+// RedeliverWebhook puts a failed (dead) delivery back in the queue with a
+// fresh retry budget. Pending and delivered ones are left alone, so a
+// subscriber never receives a delivery twice by mistake.
+func (s *Service) RedeliverWebhook(id int64) (*entities.WebhookDelivery, error) {
+	delivery, err := s.repo.GetWebhookDelivery(id)
+	if err == sql.ErrNoRows {
+		return nil, errors.New(entities.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	notFailed := &entities.DomainError{
+		Code:   entities.ErrDeliveryNotFailed,
+		Detail: "delivery is " + delivery.Status + "; only DEAD deliveries can be redelivered",
+	}
+	if delivery.Status != entities.DeliveryDead {
+		return nil, notFailed
+	}
+
+	err = s.repo.RequeueDelivery(id)
+	if err == sql.ErrNoRows {
+		// Requeued by a concurrent request since the lookup above.
+		return nil, notFailed
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetWebhookDelivery(id)
+}
+
+// DeliverWebhooks sends due deliveries from the outbox. Failed deliveries are
+// retried with exponential backoff and dead-lettered after
+// webhookMaxAttempts. It returns the number of successful deliveries.
+func (s *Service) DeliverWebhooks() (int, error) {
+	deliveries, err := s.repo.ClaimDueDeliveries(webhookBatchSize, webhookLease)
+	if err != nil {
+		return 0, err
+	}
+
+	subs := make(map[int64]*entities.WebhookSubscription)
+	secretErrs := make(map[int64]error)
+	delivered := 0
+	for _, d := range deliveries {
+		sub, ok := subs[d.SubscriptionID]
+		if !ok {
+			sub, err = s.repo.GetWebhookSubscription(d.SubscriptionID)
+			if err == sql.ErrNoRows {
+				continue
+			}
+			if err != nil {
+				return delivered, err
+			}
+			if sub.Secret, err = s.openSecret(sub.Secret); err != nil {
+				secretErrs[sub.ID] = fmt.Errorf("open secret: %w", err)
+			}
+			subs[d.SubscriptionID] = sub
+		}
+
+		event, err := s.repo.GetEvent(d.EventID)
+		if err != nil {
+			return delivered, err
+		}
+
+		statusCode, sendErr := 0, secretErrs[sub.ID]
+		if sendErr == nil {
+			statusCode, sendErr = s.sendWebhook(sub, d.ID, event)
+		}
+		if sendErr == nil {
+			err = s.repo.MarkDeliveryDelivered(d.ID, statusCode)
+			if err == nil {
+				delivered++
+			}
+		} else {
+			var code *int
+			if statusCode != 0 {
+				code = &statusCode
+			}
+			var retryIn *time.Duration
+			if d.Attempts+1 < webhookMaxAttempts {
+				backoff := webhookBackoff(d.Attempts + 1)
+				retryIn = &backoff
+			} else {
+				log.Printf("Webhook delivery %d to %s is dead after %d attempts: %v", d.ID, sub.URL, d.Attempts+1, sendErr)
+			}
+			err = s.repo.MarkDeliveryFailed(d.ID, code, sendErr.Error(), retryIn)
+		}
+		if err != nil && err != sql.ErrNoRows {
+			return delivered, err
+		}
+	}
+
+	return delivered, nil
+}
+
+// sendWebhook posts one signed payload. Any non-2xx response is a failure.
+func (s *Service) sendWebhook(sub *entities.WebhookSubscription, deliveryID int64, event *entities.PREvent) (int, error) {
+	body, err := json.Marshal(WebhookPayload{DeliveryID: deliveryID, Event: *event})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", event.Type)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(deliveryID, 10))
+	req.Header.Set("X-Webhook-Signature-256", SignWebhook(sub.Secret, body))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+func (s *Service) RunWebhookDelivery(ctx context.Context, interval time.Duration) {
+	runPeriodically(ctx, interval, "webhook deliveries", s.DeliverWebhooks)
+}
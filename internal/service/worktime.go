@@ -0,0 +1,301 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexalexbor04/pull_request_service/internal/entities"
+)
+
+const clockLayout = "15:04"
+
+// workCalendar counts business time of one reviewer: working hours on work
+// days in the reviewer's time zone, skipping the team's holidays. A nil
+// calendar counts wall-clock time.
+type workCalendar struct {
+	loc      *time.Location
+	start    time.Duration
+	end      time.Duration
+	days     [8]bool
+	holidays map[string]bool
+}
+
+// elapsed returns the business time between from and to. Database timestamps
+// carry no zone; they are written and read as UTC.
+func (c *workCalendar) elapsed(from, to time.Time) time.Duration {
+	from = time.Date(from.Year(), from.Month(), from.Day(), from.Hour(), from.Minute(), from.Second(), from.Nanosecond(), time.UTC)
+	to = time.Date(to.Year(), to.Month(), to.Day(), to.Hour(), to.Minute(), to.Second(), to.Nanosecond(), time.UTC)
+	if !to.After(from) {
+		return 0
+	}
+	if c == nil {
+		return to.Sub(from)
+	}
+
+	var total time.Duration
+	localFrom := from.In(c.loc)
+	day := time.Date(localFrom.Year(), localFrom.Month(), localFrom.Day(), 0, 0, 0, 0, c.loc)
+	for !day.After(to) {
+		if c.isWorkDay(day) {
+			opening := atClock(day, c.start)
+			closing := atClock(day, c.end)
+			if opening.Before(from) {
+				opening = from
+			}
+			if closing.After(to) {
+				closing = to
+			}
+			if closing.After(opening) {
+				total += closing.Sub(opening)
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+
+	return total
+}
+
+func (c *workCalendar) isWorkDay(day time.Time) bool {
+	weekday := int(day.Weekday())
+	if weekday == 0 {
+		weekday = 7
+	}
+	return c.days[weekday] && !c.holidays[day.Format(entities.DateLayout)]
+}
+
+// atClock returns the wall-clock time of day on the given local date, so
+// working hours stay put across DST changes.
+func atClock(day time.Time, offset time.Duration) time.Time {
+	minutes := int(offset / time.Minute)
+	return time.Date(day.Year(), day.Month(), day.Day(), minutes/60, minutes%60, 0, 0, day.Location())
+}
+
+func newWorkCalendar(ws entities.WorkSchedule, holidays []entities.TeamHoliday) (*workCalendar, error) {
+	if ws.TimeZone == "" {
+		return nil, nil
+	}
+
+	loc, err := time.LoadLocation(ws.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+	start, err := parseClock(ws.WorkStart)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseClock(ws.WorkEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &workCalendar{loc: loc, start: start, end: end, holidays: make(map[string]bool)}
+	for _, d := range ws.WorkDays {
+		if d >= 1 && d <= 7 {
+			c.days[d] = true
+		}
+	}
+	for _, h := range holidays {
+		if h.TeamName == ws.TeamName {
+			c.holidays[h.Date] = true
+		}
+	}
+
+	return c, nil
+}
+
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse(clockLayout, value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// workCalendars builds the calendars of the given reviewers. Users without a
+// time zone get no entry and are measured on the wall clock.
+func (s *Service) workCalendars(userIDs []string) (map[string]*workCalendar, error) {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, id := range userIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	schedules, err := s.repo.GetWorkSchedules(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	teamSeen := make(map[string]bool)
+	var teams []string
+	for _, ws := range schedules {
+		if ws.TimeZone != "" && !teamSeen[ws.TeamName] {
+			teamSeen[ws.TeamName] = true
+			teams = append(teams, ws.TeamName)
+		}
+	}
+	holidays, err := s.repo.GetTeamHolidays(teams...)
+	if err != nil {
+		return nil, err
+	}
+
+	calendars := make(map[string]*workCalendar, len(schedules))
+	for id, ws := range schedules {
+		c, err := newWorkCalendar(ws, holidays)
+		if err != nil {
+			return nil, fmt.Errorf("work schedule of %s: %w", id, err)
+		}
+		if c != nil {
+			calendars[id] = c
+		}
+	}
+
+	return calendars, nil
+}
+
+func (s *Service) GetWorkSchedule(userID string) (*entities.WorkSchedule, error) {
+	ws, err := s.repo.GetWorkSchedule(userID)
+	if err == sql.ErrNoRows {
+		return nil, errors.New(entities.ErrNotFound)
+	}
+	return ws, err
+}
+
+// SetWorkSchedule stores the time zone and working hours of a user. An empty
+// time zone switches the user back to wall-clock review time; omitted hours
+// and days default to 09:00-18:00, Monday to Friday.
+func (s *Service) SetWorkSchedule(ws *entities.WorkSchedule, actor string) (*entities.WorkSchedule, error) {
+	if ws.WorkStart == "" {
+		ws.WorkStart = "09:00"
+	}
+	if ws.WorkEnd == "" {
+		ws.WorkEnd = "18:00"
+	}
+	if len(ws.WorkDays) == 0 {
+		ws.WorkDays = []int{1, 2, 3, 4, 5}
+	}
+	if err := validateWorkSchedule(ws); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUser(ws.UserID)
+	if err == sql.ErrNoRows {
+		return nil, errors.New(entities.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	days := make([]string, len(ws.WorkDays))
+	for i, d := range ws.WorkDays {
+		days[i] = strconv.Itoa(d)
+	}
+	timeZone := ws.TimeZone
+	if timeZone == "" {
+		timeZone = entities.ClockWall
+	}
+
+	if err := s.repo.SetWorkSchedule(ws, entities.PREvent{
+		Type:     entities.EventUserScheduleChanged,
+		UserID:   ws.UserID,
+		TeamName: user.TeamName,
+		Actor:    actor,
+		Details: map[string]string{
+			"time_zone":  timeZone,
+			"work_hours": ws.WorkStart + "-" + ws.WorkEnd,
+			"work_days":  strings.Join(days, ","),
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetWorkSchedule(ws.UserID)
+}
+
+func validateWorkSchedule(ws *entities.WorkSchedule) error {
+	if ws.TimeZone != "" {
+		if _, err := time.LoadLocation(ws.TimeZone); err != nil {
+			return &entities.DomainError{Code: entities.ErrBadRequest, Detail: "unknown time_zone " + ws.TimeZone}
+		}
+	}
+
+	start, err := parseClock(ws.WorkStart)
+	if err != nil {
+		return &entities.DomainError{Code: entities.ErrBadRequest, Detail: "work_start must be HH:MM"}
+	}
+	end, err := parseClock(ws.WorkEnd)
+	if err != nil {
+		return &entities.DomainError{Code: entities.ErrBadRequest, Detail: "work_end must be HH:MM"}
+	}
+	if end <= start {
+		return &entities.DomainError{Code: entities.ErrBadRequest, Detail: "work_end must be after work_start"}
+	}
+
+	seen := make(map[int]bool)
+	for _, d := range ws.WorkDays {
+		if d < 1 || d > 7 || seen[d] {
+			return &entities.DomainError{Code: entities.ErrBadRequest, Detail: "work_days must be distinct ISO weekdays 1-7"}
+		}
+		seen[d] = true
+	}
+	sort.Ints(ws.WorkDays)
+
+	return nil
+}
+
+func (s *Service) GetTeamHolidays(teamName string) ([]entities.TeamHoliday, error) {
+	exists, err := s.repo.TeamExists(teamName)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.New(entities.ErrNotFound)
+	}
+
+	return s.repo.GetTeamHolidays(teamName)
+}
+
+func (s *Service) AddTeamHoliday(h *entities.TeamHoliday, actor string) error {
+	if _, err := time.Parse(entities.DateLayout, h.Date); err != nil {
+		return errors.New(entities.ErrBadRequest)
+	}
+
+	exists, err := s.repo.TeamExists(h.TeamName)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New(entities.ErrNotFound)
+	}
+
+	return s.repo.AddTeamHoliday(h, entities.PREvent{
+		Type:     entities.EventTeamHolidaysChanged,
+		TeamName: h.TeamName,
+		Actor:    actor,
+		Details:  map[string]string{"added": h.Date, "name": h.Name},
+	})
+}
+
+func (s *Service) DeleteTeamHoliday(teamName, date, actor string) error {
+	if _, err := time.Parse(entities.DateLayout, date); err != nil {
+		return errors.New(entities.ErrBadRequest)
+	}
+
+	err := s.repo.DeleteTeamHoliday(teamName, date, entities.PREvent{
+		Type:     entities.EventTeamHolidaysChanged,
+		TeamName: teamName,
+		Actor:    actor,
+		Details:  map[string]string{"removed": date},
+	})
+	if err == sql.ErrNoRows {
+		return errors.New(entities.ErrNotFound)
+	}
+	return err
+}
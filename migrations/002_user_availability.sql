@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS user_availability (
+    id SERIAL PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CHECK (end_date >= start_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_availability_user_id ON user_availability(user_id);
+CREATE INDEX IF NOT EXISTS idx_user_availability_dates ON user_availability(start_date, end_date);
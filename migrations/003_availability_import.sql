@@ -0,0 +1,3 @@
+ALTER TABLE user_availability ADD COLUMN IF NOT EXISTS source_uid VARCHAR(512);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_availability_source_uid ON user_availability(source_uid);
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS review_weight DOUBLE PRECISION NOT NULL DEFAULT 1.0 CHECK (review_weight > 0);
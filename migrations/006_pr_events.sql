@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS pr_events (
+    id BIGSERIAL PRIMARY KEY,
+    event_type VARCHAR(64) NOT NULL,
+    pull_request_id VARCHAR(255),
+    user_id VARCHAR(255),
+    old_user_id VARCHAR(255),
+    new_user_id VARCHAR(255),
+    team_name VARCHAR(255),
+    reason VARCHAR(255),
+    actor VARCHAR(255),
+    details JSONB,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_pr_events_pr_id ON pr_events(pull_request_id, id);
+CREATE INDEX IF NOT EXISTS idx_pr_events_type ON pr_events(event_type, id);
+CREATE INDEX IF NOT EXISTS idx_pr_events_team ON pr_events(team_name, id);
+CREATE INDEX IF NOT EXISTS idx_pr_events_created_at ON pr_events(created_at);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS pr_declines (
+    id SERIAL PRIMARY KEY,
+    pull_request_id VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    reason VARCHAR(64) NOT NULL,
+    comment TEXT NOT NULL DEFAULT '',
+    declined_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (pull_request_id) REFERENCES pull_requests(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    UNIQUE(pull_request_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pr_declines_user_id ON pr_declines(user_id);
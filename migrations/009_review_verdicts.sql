@@ -0,0 +1,4 @@
+ALTER TABLE pr_reviewers ADD COLUMN IF NOT EXISTS verdict VARCHAR(32) CHECK (verdict IN ('APPROVED', 'CHANGES_REQUESTED'));
+ALTER TABLE pr_reviewers ADD COLUMN IF NOT EXISTS verdict_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_pr_reviewers_user_assigned ON pr_reviewers(user_id, assigned_at, pull_request_id);
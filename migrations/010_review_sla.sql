@@ -0,0 +1,4 @@
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS review_sla_hours INTEGER CHECK (review_sla_hours > 0);
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS reassign_after_hours INTEGER CHECK (reassign_after_hours > 0);
+
+ALTER TABLE pr_reviewers ADD COLUMN IF NOT EXISTS sla_breached_at TIMESTAMP;
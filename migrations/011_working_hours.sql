@@ -0,0 +1,12 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS time_zone VARCHAR(64);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS work_start TIME NOT NULL DEFAULT '09:00';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS work_end TIME NOT NULL DEFAULT '18:00';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS work_days SMALLINT[] NOT NULL DEFAULT '{1,2,3,4,5}';
+
+CREATE TABLE IF NOT EXISTS team_holidays (
+    team_name VARCHAR(255) NOT NULL,
+    day DATE NOT NULL,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    PRIMARY KEY (team_name, day),
+    FOREIGN KEY (team_name) REFERENCES teams(team_name) ON DELETE CASCADE
+);
@@ -0,0 +1,20 @@
+-- 001 only allows OPEN and MERGED; replace its check once.
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conrelid = 'pull_requests'::regclass AND conname = 'pull_requests_status_check'
+            AND pg_get_constraintdef(oid) LIKE '%CLOSED%'
+    ) THEN
+        ALTER TABLE pull_requests DROP CONSTRAINT IF EXISTS pull_requests_status_check;
+        ALTER TABLE pull_requests ADD CONSTRAINT pull_requests_status_check CHECK (status IN ('OPEN', 'MERGED', 'CLOSED'));
+    END IF;
+END $$;
+
+ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS labels TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS closed_at TIMESTAMP;
+ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS stale_warned_at TIMESTAMP;
+
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS stale_warn_days INTEGER CHECK (stale_warn_days > 0);
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS stale_close_days INTEGER CHECK (stale_close_days > 0);
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS stale_exempt_label VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS user_logins (
+    provider VARCHAR(32) NOT NULL,
+    login VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (provider, login),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_logins_user_id ON user_logins(user_id);
+
+CREATE TABLE IF NOT EXISTS inbound_deliveries (
+    provider VARCHAR(32) NOT NULL,
+    delivery_id VARCHAR(255) NOT NULL,
+    received_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (provider, delivery_id)
+);
@@ -0,0 +1,24 @@
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS chat_webhook_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS chat_template TEXT NOT NULL DEFAULT '';
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS chat_digest_at TIME;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS chat_mention VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id BIGSERIAL PRIMARY KEY,
+    channel VARCHAR(16) NOT NULL,
+    kind VARCHAR(16) NOT NULL CHECK (kind IN ('event', 'digest')),
+    team_name VARCHAR(255) NOT NULL,
+    event_id BIGINT,
+    digest_date DATE,
+    status VARCHAR(16) NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'SENT', 'DEAD')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT NOT NULL DEFAULT '',
+    sent_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (team_name) REFERENCES teams(team_name) ON DELETE CASCADE,
+    FOREIGN KEY (event_id) REFERENCES pr_events(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_due ON notifications(status, next_attempt_at);
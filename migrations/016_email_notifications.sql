@@ -0,0 +1,25 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_opt_out TEXT[] NOT NULL DEFAULT '{}';
+
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS user_id VARCHAR(255) REFERENCES users(id) ON DELETE CASCADE;
+
+-- Databases set up by an earlier version of 015 have a chat-only channel check
+-- and a digest index without the recipient; replace them once.
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conrelid = 'notifications'::regclass AND conname = 'notifications_channel_check'
+            AND pg_get_constraintdef(oid) LIKE '%email%'
+    ) THEN
+        ALTER TABLE notifications DROP CONSTRAINT IF EXISTS notifications_channel_check;
+        ALTER TABLE notifications ADD CONSTRAINT notifications_channel_check CHECK (channel IN ('chat', 'email'));
+    END IF;
+
+    IF to_regclass('idx_notifications_digest') IS NOT NULL THEN
+        DROP INDEX idx_notifications_digest;
+    END IF;
+END $$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notifications_digest_recipient
+    ON notifications(channel, team_name, coalesce(user_id, ''), digest_date) WHERE kind = 'digest';